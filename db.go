@@ -8,15 +8,31 @@ import (
 	"context"
 	"database/sql"
 	"strings"
+	"time"
+
+	"github.com/issue9/orm/internal/lexer"
 )
 
 // DB 数据库操作实例。
 type DB struct {
-	stdDB       *sql.DB
-	dialect     Dialect
-	tablePrefix string
-	replacer    *strings.Replacer
-	sql         *SQL
+	stdDB        *sql.DB
+	dialect      Dialect
+	tablePrefix  string
+	sql          *SQL
+	interceptors []Interceptor
+	clock        func() time.Time
+	stmts        *stmtCache // 预编译语句的缓存，为 nil 表示未启用
+
+	// 只读的从数据库，每个实例会根据其权重重复出现多次。
+	replicas    []*sql.DB
+	replicaNext uint32
+}
+
+// sql.DB 与 sql.Tx 的共有接口
+type stdEngine interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
 }
 
 // NewDB 声明一个新的 DB 实例。
@@ -31,31 +47,61 @@ func NewDB(driverName, dataSourceName, tablePrefix string, dialect Dialect) (*DB
 
 // NewDBWithStdDB 从 sql.DB 构建一个 DB 实例。
 func NewDBWithStdDB(db *sql.DB, tablePrefix string, dialect Dialect) (*DB, error) {
-	l, r := dialect.QuoteTuple()
 	inst := &DB{
 		stdDB:       db,
 		dialect:     dialect,
 		tablePrefix: tablePrefix,
-		replacer: strings.NewReplacer(
-			"#", tablePrefix,
-			"{", string(l),
-			"}", string(r),
-		),
 	}
 	inst.sql = &SQL{engine: inst}
 
 	return inst, nil
 }
 
+// SetClock 指定获取当前时间的函数，
+// 用于填充 created、updated 以及软删除等列的值，默认为 time.Now。
+//
+// 非协程安全，应该在初始化 DB 之后，执行其它语句之前调用。
+func (db *DB) SetClock(clock func() time.Time) {
+	db.clock = clock
+}
+
+func (db *DB) now() time.Time {
+	if db.clock == nil {
+		return time.Now()
+	}
+	return db.clock()
+}
+
 // Close 关闭当前数据库，释放所有的链接。
 //
 // 关闭之后，之前通过 DB.StdDB() 返回的实例也将失效。
 // 通过调用 DB.StdDB().Close() 也将使当前实例失效。
+// 若存在从数据库，也会一并关闭。
 func (db *DB) Close() error {
+	if db.stmts != nil {
+		if err := db.stmts.close(); err != nil {
+			return err
+		}
+	}
+
+	closed := make(map[*sql.DB]bool, len(db.replicas))
+	for _, r := range db.replicas {
+		if closed[r] {
+			continue
+		}
+		closed[r] = true
+
+		if err := r.Close(); err != nil {
+			return err
+		}
+	}
+
 	return db.stdDB.Close()
 }
 
 // StdDB 返回标准包中的 sql.DB 指针。
+//
+// 若存在从数据库，返回的是主数据库。
 func (db *DB) StdDB() *sql.DB {
 	return db.stdDB
 }
@@ -68,73 +114,221 @@ func (db *DB) Dialect() Dialect {
 // Query 执行一条查询语句，并返回相应的 sql.Rows 实例。
 // 具体参数说明可参考 Engine 接口文档。
 func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	query = db.replacer.Replace(query)
-	query, err := db.dialect.SQL(query)
-	if err != nil {
-		return nil, err
-	}
-
-	return db.stdDB.Query(query, args...)
+	return db.QueryContext(context.Background(), query, args...)
 }
 
 // QueryContext 执行一条查询语句，并返回相应的 sql.Rows 实例。
+//
+// 若存在从数据库，则 SELECT 语句会在从数据库上执行，可以通过 WithPrimary()
+// 包装 ctx 强制在主数据库上执行。
 func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	query = db.replacer.Replace(query)
-	query, err := db.dialect.SQL(query)
-	if err != nil {
-		return nil, err
-	}
-
-	return db.stdDB.QueryContext(ctx, query, args...)
+	return db.query(ctx, db.readDB(ctx, query), query, args...)
 }
 
 // Exec 执行 SQL 语句。
 func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
-	query = db.replacer.Replace(query)
-	query, err := db.dialect.SQL(query)
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext 执行 SQL 语句。
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.exec(ctx, db.stdDB, query, args...)
+}
+
+// Prepare 预编译查询语句。
+func (db *DB) Prepare(query string) (*sql.Stmt, error) {
+	return db.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext 预编译查询语句。
+func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return db.prepare(ctx, db.stdDB, query)
+}
+
+// 将 query 中的占位符替换成当前环境下的实际内容，并转换成当前数据库支持的语法，
+// args 也会根据语句中的命名参数作相应的调整。
+func (db *DB) translate(query string, args []interface{}) (string, []interface{}, error) {
+	args, err := namedArgs(args)
 	if err != nil {
-		return nil, err
+		return "", nil, err
 	}
 
-	return db.stdDB.Exec(query, args...)
+	return db.dialect.SQL(db.replace(query), args)
 }
 
-// ExecContext 执行 SQL 语句。
-func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	query = db.replacer.Replace(query)
-	query, err := db.dialect.SQL(query)
+// 将 query 中的 # 替换成表名前缀，{ 和 } 替换成标识符的引号，## 则表示 # 本身。
+//
+// 字符串、引号包含的标识符以及注释中的内容保持不变。
+func (db *DB) replace(query string) string {
+	if strings.IndexAny(query, "#{}") < 0 {
+		return query
+	}
+
+	l, r := db.dialect.QuoteTuple()
+	buf := make([]byte, 0, len(query)+len(db.tablePrefix)*2)
+	for _, token := range lexer.Split(query, db.dialect.BackslashEscapes()) {
+		if token.Kind != lexer.Code {
+			buf = append(buf, token.Text...)
+			continue
+		}
+
+		text := token.Text
+		for i := 0; i < len(text); i++ {
+			switch c := text[i]; c {
+			case '#':
+				if i+1 < len(text) && text[i+1] == '#' {
+					buf = append(buf, '#')
+					i++
+				} else {
+					buf = append(buf, db.tablePrefix...)
+				}
+			case '{':
+				buf = append(buf, l)
+			case '}':
+				buf = append(buf, r)
+			default:
+				buf = append(buf, c)
+			}
+		}
+	}
+
+	return string(buf)
+}
+
+func (db *DB) query(ctx context.Context, e stdEngine, query string, args ...interface{}) (*sql.Rows, error) {
+	query, args, err := db.translate(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, release, err := db.cachedStmt(ctx, e, query)
 	if err != nil {
 		return nil, err
 	}
+	if stmt != nil {
+		defer release()
+	}
 
-	return db.stdDB.ExecContext(ctx, query, args...)
+	return db.queryStmt(ctx, e, stmt, query, args)
 }
 
-// Prepare 预编译查询语句。
-func (db *DB) Prepare(query string) (*sql.Stmt, error) {
-	query = db.replacer.Replace(query)
-	query, err := db.dialect.SQL(query)
+// 执行已经转换过的查询语句 query，stmt 不为空时，通过 stmt 执行。
+func (db *DB) queryStmt(ctx context.Context, e stdEngine, stmt *sql.Stmt, query string, args []interface{}) (*sql.Rows, error) {
+	if len(db.interceptors) == 0 {
+		if stmt != nil {
+			return stmt.QueryContext(ctx, args...)
+		}
+		return e.QueryContext(ctx, query, args...)
+	}
+
+	start := time.Now()
+	var rows *sql.Rows
+	var err error
+	if stmt != nil {
+		rows, err = stmt.QueryContext(ctx, args...)
+	} else {
+		rows, err = e.QueryContext(ctx, query, args...)
+	}
+	db.intercept(ctx, e, EventQuery, query, args, start, -1, err)
+	return rows, err
+}
+
+func (db *DB) exec(ctx context.Context, e stdEngine, query string, args ...interface{}) (sql.Result, error) {
+	query, args, err := db.translate(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, release, err := db.cachedStmt(ctx, e, query)
 	if err != nil {
 		return nil, err
 	}
+	if stmt != nil {
+		defer release()
+	}
 
-	return db.stdDB.Prepare(query)
+	return db.execStmt(ctx, e, stmt, query, args)
 }
 
-// PrepareContext 预编译查询语句。
-func (db *DB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
-	query = db.replacer.Replace(query)
-	query, err := db.dialect.SQL(query)
+// 执行已经转换过的语句 query，stmt 不为空时，通过 stmt 执行。
+func (db *DB) execStmt(ctx context.Context, e stdEngine, stmt *sql.Stmt, query string, args []interface{}) (sql.Result, error) {
+	if len(db.interceptors) == 0 {
+		if stmt != nil {
+			return stmt.ExecContext(ctx, args...)
+		}
+		return e.ExecContext(ctx, query, args...)
+	}
+
+	start := time.Now()
+	var r sql.Result
+	var err error
+	if stmt != nil {
+		r, err = stmt.ExecContext(ctx, args...)
+	} else {
+		r, err = e.ExecContext(ctx, query, args...)
+	}
+	var affected int64 = -1
+	if err == nil {
+		if cnt, err := r.RowsAffected(); err == nil {
+			affected = cnt
+		}
+	}
+	db.intercept(ctx, e, EventExec, query, args, start, affected, err)
+	return r, err
+}
+
+func (db *DB) prepare(ctx context.Context, e stdEngine, query string) (*sql.Stmt, error) {
+	query, _, err := db.translate(query, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return db.stdDB.PrepareContext(ctx, query)
+	if len(db.interceptors) == 0 {
+		return e.PrepareContext(ctx, query)
+	}
+
+	start := time.Now()
+	stmt, err := e.PrepareContext(ctx, query)
+	db.intercept(ctx, e, EventPrepare, query, nil, start, -1, err)
+	return stmt, err
 }
 
 // Insert 插入数据，若需一次性插入多条数据，请使用 tx.Insert()。
 func (db *DB) Insert(v interface{}) (sql.Result, error) {
-	return insert(db, v)
+	return db.InsertContext(context.Background(), v)
+}
+
+// InsertContext 插入数据，若需一次性插入多条数据，请使用 tx.Insert()。
+func (db *DB) InsertContext(ctx context.Context, v interface{}) (sql.Result, error) {
+	return insert(ctx, db, v)
+}
+
+// InsertMany 插入多条相同的数据，具体说明可参考 Tx.InsertMany()。
+//
+// 所有的插入操作都在同一个事务中完成。
+func (db *DB) InsertMany(v interface{}) error {
+	return db.InsertManyContext(context.Background(), v)
+}
+
+// InsertManyContext 插入多条相同的数据。
+func (db *DB) InsertManyContext(ctx context.Context, v interface{}) error {
+	return db.DoTransaction(ctx, nil, func(tx *Tx) error {
+		return tx.InsertManyContext(ctx, v)
+	})
+}
+
+// Upsert 插入数据，若与已有数据冲突，则更新已有数据。
+//
+// 以 v 中值不为零值的主键或是唯一约束作为冲突检测的列，
+// cols 为冲突时需要更新的列，若未指定，则更新除冲突检测列之外的所有插入列。
+// 不会调用 BeforeInserter 等钩子接口。
+func (db *DB) Upsert(v interface{}, cols ...string) (sql.Result, error) {
+	return db.UpsertContext(context.Background(), v, cols...)
+}
+
+// UpsertContext 插入数据，若与已有数据冲突，则更新已有数据。
+func (db *DB) UpsertContext(ctx context.Context, v interface{}, cols ...string) (sql.Result, error) {
+	return upsert(ctx, db, v, cols...)
 }
 
 // Delete 删除符合条件的数据。
@@ -142,15 +336,40 @@ func (db *DB) Insert(v interface{}) (sql.Result, error) {
 // 查找条件以结构体定义的主键或是唯一约束(在没有主键的情况下)来查找，
 // 若两者都不存在，则将返回 error
 func (db *DB) Delete(v interface{}) (sql.Result, error) {
-	return del(db, v)
+	return db.DeleteContext(context.Background(), v)
+}
+
+// DeleteContext 删除符合条件的数据。
+func (db *DB) DeleteContext(ctx context.Context, v interface{}) (sql.Result, error) {
+	return del(ctx, db, v)
+}
+
+// HardDelete 删除符合条件的数据。
+//
+// 与 Delete() 的区别在于，即使 v 包含软删除列，也会直接从数据库中删除数据。
+func (db *DB) HardDelete(v interface{}) (sql.Result, error) {
+	return db.HardDeleteContext(context.Background(), v)
+}
+
+// HardDeleteContext 删除符合条件的数据。
+func (db *DB) HardDeleteContext(ctx context.Context, v interface{}) (sql.Result, error) {
+	return del(Unscoped(ctx), db, v)
 }
 
 // Update 更新数据，零值不会被提交，cols 指定的列，即使是零值也会被更新。
 //
 // 查找条件以结构体定义的主键或是唯一约束(在没有主键的情况下)来查找，
 // 若两者都不存在，则将返回 error
+//
+// 若 v 包含乐观锁列，在数据已经被其它操作修改时，返回 ErrOCCConflict；
+// 更新成功，则会将 v 中乐观锁列的值加 1。
 func (db *DB) Update(v interface{}, cols ...string) (sql.Result, error) {
-	return update(db, v, cols...)
+	return db.UpdateContext(context.Background(), v, cols...)
+}
+
+// UpdateContext 更新数据，零值不会被提交，cols 指定的列，即使是零值也会被更新。
+func (db *DB) UpdateContext(ctx context.Context, v interface{}, cols ...string) (sql.Result, error) {
+	return update(ctx, db, v, cols...)
 }
 
 // Select 查询一个符合条件的数据。
@@ -159,43 +378,90 @@ func (db *DB) Update(v interface{}, cols ...string) (sql.Result, error) {
 // 若两者都不存在，则将返回 error
 // 若没有符合条件的数据，将不会对参数v做任何变动。
 func (db *DB) Select(v interface{}) error {
-	return find(db, v)
+	return db.SelectContext(context.Background(), v)
+}
+
+// SelectContext 查询一个符合条件的数据。
+func (db *DB) SelectContext(ctx context.Context, v interface{}) error {
+	return find(ctx, db, v)
+}
+
+// Find 查询所有符合 example 条件的数据，并写入到 objs 中。
+//
+// example 中的所有非零字段都将参与查询，若都为零值，则表示查询所有数据；
+// objs 的类型可以参考 github.com/issue9/orm/fetch.Obj 函数的相关介绍；
+// opts 用于指定排序和分页等选项，可以为 nil。
+// 若 opts.Count 为 true，返回值为不考虑分页时符合条件的记录总数，
+// 否则为实际写入到 objs 中的记录数量。
+func (db *DB) Find(example, objs interface{}, opts *FindOptions) (int64, error) {
+	return db.FindContext(context.Background(), example, objs, opts)
+}
+
+// FindContext 查询所有符合 example 条件的数据，并写入到 objs 中。
+func (db *DB) FindContext(ctx context.Context, example, objs interface{}, opts *FindOptions) (int64, error) {
+	return findMany(ctx, db, example, objs, opts)
+}
+
+// Preload 为 objs 加载 paths 指定的关联数据。
+//
+// objs 可以是结构体指针，或是由结构体(指针)组成的切片(指针)；
+// paths 为关联关系所在的字段名，可以用点号分隔表示嵌套的关联关系，比如 Posts.Comments。
+// 每一层关联关系仅执行一次 IN 查询，而不是为每条记录都查询一次。
+func (db *DB) Preload(objs interface{}, paths ...string) error {
+	return db.PreloadContext(context.Background(), objs, paths...)
+}
+
+// PreloadContext 为 objs 加载 paths 指定的关联数据。
+func (db *DB) PreloadContext(ctx context.Context, objs interface{}, paths ...string) error {
+	return preload(ctx, db, objs, paths)
 }
 
 // Count 查询符合 v 条件的记录数量。
 // v 中的所有非零字段都将参与查询。
 // 若需要复杂的查询方式，请构建 SelectStmt 对象查询。
 func (db *DB) Count(v interface{}) (int64, error) {
-	return count(db, v)
+	return db.CountContext(context.Background(), v)
+}
+
+// CountContext 查询符合 v 条件的记录数量。
+func (db *DB) CountContext(ctx context.Context, v interface{}) (int64, error) {
+	return count(ctx, db, v)
 }
 
 // Create 创建一张表。
 func (db *DB) Create(v interface{}) error {
-	if !db.Dialect().TransactionalDDL() {
-		return create(db, v)
-	}
-
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
+	return db.CreateContext(context.Background(), v)
+}
 
-	if err = create(tx, v); err != nil {
-		tx.Rollback()
-		return err
+// CreateContext 创建一张表。
+func (db *DB) CreateContext(ctx context.Context, v interface{}) error {
+	if !db.Dialect().TransactionalDDL() {
+		return create(ctx, db, v)
 	}
 
-	return tx.Commit()
+	return db.DoTransaction(ctx, nil, func(tx *Tx) error {
+		return create(ctx, tx, v)
+	})
 }
 
 // Drop 删除一张表。
 func (db *DB) Drop(v interface{}) error {
-	return drop(db, v)
+	return db.DropContext(context.Background(), v)
+}
+
+// DropContext 删除一张表。
+func (db *DB) DropContext(ctx context.Context, v interface{}) error {
+	return drop(ctx, db, v)
 }
 
 // Truncate 清空一张表。
 func (db *DB) Truncate(v interface{}) error {
-	return truncate(db, v)
+	return db.TruncateContext(context.Background(), v)
+}
+
+// TruncateContext 清空一张表。
+func (db *DB) TruncateContext(ctx context.Context, v interface{}) error {
+	return truncate(ctx, db, v)
 }
 
 // SQL 返回 SQL 实例
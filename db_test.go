@@ -5,8 +5,14 @@
 package orm_test
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/issue9/assert"
 	"github.com/issue9/conv"
@@ -14,6 +20,7 @@ import (
 	"github.com/issue9/orm/dialect"
 	"github.com/issue9/orm/fetch"
 	"github.com/issue9/orm/internal/modeltest"
+	"github.com/issue9/orm/sqlbuilder"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
@@ -22,6 +29,161 @@ import (
 
 var _ orm.Engine = &orm.DB{}
 
+var _ orm.Tracker = &trackedUser{}
+
+var (
+	_ orm.BeforeInserter = &hookUser{}
+	_ orm.AfterInserter  = &hookUser{}
+	_ orm.BeforeUpdater  = &hookUser{}
+	_ orm.AfterUpdater   = &hookUser{}
+	_ orm.BeforeDeleter  = &hookUser{}
+	_ orm.AfterDeleter   = &hookUser{}
+	_ orm.AfterFinder    = &hookUser{}
+)
+
+// 实现了所有钩子接口的对象
+type hookUser struct {
+	ID   int64  `orm:"name(id);ai"`
+	Name string `orm:"name(name);len(50)"`
+
+	events []string
+}
+
+// 带软删除列的对象
+type softUser struct {
+	ID      int64     `orm:"name(id);ai"`
+	Name    string    `orm:"name(name);len(50)"`
+	Deleted time.Time `orm:"name(deleted);nullable;softdelete"`
+}
+
+func (u *softUser) Meta() string {
+	return "name(soft_users)"
+}
+
+// 带乐观锁的对象
+type occUser struct {
+	ID      int64  `orm:"name(id);ai"`
+	Name    string `orm:"name(name);len(50)"`
+	Version int64  `orm:"name(version);occ"`
+}
+
+func (u *occUser) Meta() string {
+	return "name(occ_users)"
+}
+
+// 带快照的对象
+type trackedUser struct {
+	orm.Tracked
+	ID   int64  `orm:"name(id);ai"`
+	Name string `orm:"name(name);len(50)"`
+	Age  int    `orm:"name(age)"`
+}
+
+func (u *trackedUser) Meta() string {
+	return "name(tracked_users)"
+}
+
+// 带关联关系的对象
+type relUser struct {
+	ID    int64      `orm:"name(id);ai"`
+	Name  string     `orm:"name(name);len(50)"`
+	Posts []*relPost `orm:"hasmany(fk_rel_posts_uid)"`
+}
+
+type relPost struct {
+	ID       int64        `orm:"name(id);ai"`
+	UID      int64        `orm:"name(uid);fk(fk_rel_posts_uid,#rel_users,id)"`
+	Title    string       `orm:"name(title);len(50)"`
+	Author   *relUser     `orm:"belongsto(fk_rel_posts_uid)"`
+	Comments []relComment `orm:"hasmany(fk_rel_comments_pid)"`
+	Tags     []*relTag    `orm:"manytomany(#rel_post_tags,pid,tid)"`
+}
+
+type relComment struct {
+	ID      int64  `orm:"name(id);ai"`
+	PID     int64  `orm:"name(pid);fk(fk_rel_comments_pid,#rel_posts,id)"`
+	Content string `orm:"name(content);len(50)"`
+}
+
+type relTag struct {
+	ID   int64  `orm:"name(id);ai"`
+	Name string `orm:"name(name);len(50)"`
+}
+
+// 外键引用了不存在的列或是其它表的关联关系
+type badRefPost struct {
+	ID     int64    `orm:"name(id);ai"`
+	UID    int64    `orm:"name(uid);fk(fk_bad_ref_uid,#rel_users,not_exists)"`
+	TID    int64    `orm:"name(tid);fk(fk_bad_ref_tid,#rel_tags,id)"`
+	Author *relUser `orm:"belongsto(fk_bad_ref_uid)"`
+	Tag    *relUser `orm:"belongsto(fk_bad_ref_tid)"`
+}
+
+type relPostTag struct {
+	PID int64 `orm:"name(pid)"`
+	TID int64 `orm:"name(tid)"`
+}
+
+func (u *relUser) Meta() string    { return "name(rel_users)" }
+func (p *relPost) Meta() string    { return "name(rel_posts)" }
+func (c *relComment) Meta() string { return "name(rel_comments)" }
+func (t *relTag) Meta() string     { return "name(rel_tags)" }
+func (t *relPostTag) Meta() string { return "name(rel_post_tags)" }
+
+// 带创建时间和更新时间的对象
+type timeUser struct {
+	ID      int64     `orm:"name(id);ai"`
+	Name    string    `orm:"name(name);len(50)"`
+	Created time.Time `orm:"name(created);created"`
+	Updated int64     `orm:"name(updated);updated"`
+}
+
+func (u *timeUser) Meta() string {
+	return "name(time_users)"
+}
+
+func (u *hookUser) Meta() string {
+	return "name(hook_users)"
+}
+
+func (u *hookUser) BeforeInsert(e orm.Engine) error {
+	if u.Name == "" {
+		return errors.New("name 不能为空")
+	}
+	u.events = append(u.events, "BeforeInsert")
+	return nil
+}
+
+func (u *hookUser) AfterInsert(e orm.Engine) error {
+	u.events = append(u.events, "AfterInsert")
+	return nil
+}
+
+func (u *hookUser) BeforeUpdate(e orm.Engine) error {
+	u.events = append(u.events, "BeforeUpdate")
+	return nil
+}
+
+func (u *hookUser) AfterUpdate(e orm.Engine) error {
+	u.events = append(u.events, "AfterUpdate")
+	return nil
+}
+
+func (u *hookUser) BeforeDelete(e orm.Engine) error {
+	u.events = append(u.events, "BeforeDelete")
+	return nil
+}
+
+func (u *hookUser) AfterDelete(e orm.Engine) error {
+	u.events = append(u.events, "AfterDelete")
+	return nil
+}
+
+func (u *hookUser) AfterFind(e orm.Engine) error {
+	u.events = append(u.events, "AfterFind")
+	return nil
+}
+
 var (
 	// 通过修改此值来确定使用哪个数据库驱动来测试
 	// 若需要其它两种数据库测试，需要先在创建相应的数据库
@@ -87,6 +249,96 @@ func TestNewDB(t *testing.T) {
 	a.NotNil(db.StdDB()).NotNil(db.Dialect())
 }
 
+func TestDB_Query_literal(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	queries := []string{}
+	db.AddInterceptor(orm.InterceptorFunc(func(ctx context.Context, e *orm.Event) {
+		queries = append(queries, e.Query)
+	}))
+
+	l, r := db.Dialect().QuoteTuple()
+	quote := func(col string) string { return string(l) + col + string(r) }
+
+	// 字符串和注释中的 #、{ 和 } 保持不变
+	_, err := db.Exec("INSERT INTO #user_info({uid},{firstName},{lastName},{sex}) VALUES(3,'a#b','{\"k\":1}','#') /* {#} */")
+	a.NotError(err)
+	a.Equal(queries[0], "INSERT INTO "+prefix+"user_info("+quote("uid")+","+quote("firstName")+","+quote("lastName")+","+quote("sex")+") VALUES(3,'a#b','{\"k\":1}','#') /* {#} */")
+
+	rows, err := db.Query("SELECT * FROM #user_info WHERE {firstName}='a#b' -- {#}\n AND {lastName}='{\"k\":1}'")
+	a.NotError(err)
+	us := []*modeltest.UserInfo{}
+	cnt, err := fetch.Obj(&us, rows)
+	a.NotError(err).NotError(rows.Close())
+	a.Equal(cnt, 1).Equal(us[0].UID, 3).Equal(us[0].Sex, "#")
+
+	// ## 表示 # 本身
+	_, err = db.Query("SELECT ##, {x}")
+	a.Error(err)
+	a.Equal(queries[2], "SELECT #, "+quote("x"))
+}
+
+func TestDB_Query_named(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	// 结构体
+	_, err := db.Exec("INSERT INTO #user_info({uid},{firstName},{lastName},{sex}) VALUES(@uid,@firstName,@lastName,@sex)",
+		&modeltest.UserInfo{UID: 3, FirstName: "f3", LastName: "l3", Sex: "female"})
+	a.NotError(err)
+
+	// map，同一参数多次使用
+	rows, err := db.Query("SELECT * FROM #user_info WHERE {uid}=@uid OR ({firstName}=@name AND {lastName}<>@name) ORDER BY {uid}",
+		map[string]interface{}{"uid": 1, "name": "f3"})
+	a.NotError(err)
+	us := []*modeltest.UserInfo{}
+	cnt, err := fetch.Obj(&us, rows)
+	a.NotError(err).NotError(rows.Close())
+	a.Equal(cnt, 2).Equal(us[0].UID, 1).Equal(us[1].UID, 3)
+
+	// sql.NamedArg 与普通参数混用
+	tx, err := db.Begin()
+	a.NotError(err)
+	r, err := tx.Exec("UPDATE #user_info SET {sex}=? WHERE {uid}=@uid", sql.Named("uid", 3), "male")
+	a.NotError(err)
+	affected, err := r.RowsAffected()
+	a.NotError(err).Equal(affected, 1)
+	a.NotError(tx.Commit())
+
+	u := &modeltest.UserInfo{UID: 3}
+	a.NotError(db.Select(u))
+	a.Equal(u.Sex, "male")
+
+	// 单个 sql.NamedArg 不会被当作模型展开
+	rows, err = db.Query("SELECT * FROM #user_info WHERE {uid}=@uid", sql.Named("uid", 3))
+	a.NotError(err)
+	us = []*modeltest.UserInfo{}
+	cnt, err = fetch.Obj(&us, rows)
+	a.NotError(err).NotError(rows.Close())
+	a.Equal(cnt, 1).Equal(us[0].UID, 3)
+
+	// 非模型的结构体作为普通参数
+	rows, err = db.Query("SELECT * FROM #user_info WHERE {sex}=?", sql.NullString{String: "male", Valid: true})
+	a.NotError(err)
+	us = []*modeltest.UserInfo{}
+	cnt, err = fetch.Obj(&us, rows)
+	a.NotError(err).NotError(rows.Close())
+	a.True(cnt > 0)
+
+	// 单个命名参数作为 Limit 的值
+	sel := db.SQL().Select().Select("*").From("{#user_info}").Limit(sql.Named("limit", 1))
+	us = []*modeltest.UserInfo{}
+	cnt, _, _, err = sel.QueryObj(&us)
+	a.NotError(err).Equal(cnt, 1)
+}
+
 // 初始化测试数据，同时可当作 DB.Inert 的测试
 // 清空其它数据，初始化成原始的测试数据
 func initData(db *orm.DB, a *assert.Assertion) {
@@ -296,3 +548,634 @@ func TestDB_Drop(t *testing.T) {
 	r, err := db.Insert(&modeltest.Admin{})
 	a.Error(err).Nil(r)
 }
+
+func TestDB_Context(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	ctx := context.Background()
+	r, err := db.InsertContext(ctx, &modeltest.UserInfo{UID: 3, FirstName: "f3", LastName: "l3"})
+	a.NotError(err).NotNil(r)
+
+	u3 := &modeltest.UserInfo{UID: 3}
+	a.NotError(db.SelectContext(ctx, u3))
+	a.Equal(u3, &modeltest.UserInfo{UID: 3, FirstName: "f3", LastName: "l3", Sex: "male"})
+
+	count, err := db.CountContext(ctx, &modeltest.UserInfo{Sex: "male"})
+	a.NotError(err).Equal(2, count)
+
+	// 已取消的 context
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	r, err = db.InsertContext(ctx, &modeltest.UserInfo{UID: 4, FirstName: "f4", LastName: "l4"})
+	a.Error(err).Nil(r)
+	a.Error(db.SelectContext(ctx, &modeltest.UserInfo{UID: 3}))
+	hasCount(db, a, "user_info", 3)
+}
+
+func TestDB_hooks(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer func() {
+		a.NotError(db.Drop(&hookUser{}))
+		clearData(db, a)
+	}()
+	a.NotError(db.Create(&hookUser{}))
+
+	// BeforeInsert 返回错误，中止插入
+	r, err := db.Insert(&hookUser{})
+	a.Error(err).Nil(r)
+	hasCount(db, a, "hook_users", 0)
+
+	u := &hookUser{Name: "u1"}
+	_, err = db.Insert(u)
+	a.NotError(err)
+	a.Equal(u.events, []string{"BeforeInsert", "AfterInsert"})
+
+	u = &hookUser{ID: 1}
+	a.NotError(db.Select(u))
+	a.Equal(u.Name, "u1").Equal(u.events, []string{"AfterFind"})
+
+	// 不存在的数据，不会调用 AfterFind
+	u = &hookUser{ID: 100}
+	a.NotError(db.Select(u))
+	a.Empty(u.events)
+
+	u = &hookUser{ID: 1, Name: "u2"}
+	_, err = db.Update(u)
+	a.NotError(err)
+	a.Equal(u.events, []string{"BeforeUpdate", "AfterUpdate"})
+
+	u = &hookUser{ID: 1}
+	_, err = db.Delete(u)
+	a.NotError(err)
+	a.Equal(u.events, []string{"BeforeDelete", "AfterDelete"})
+	hasCount(db, a, "hook_users", 0)
+}
+
+func TestDB_Find(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	a.NotError(db.InsertMany([]*modeltest.UserInfo{
+		&modeltest.UserInfo{UID: 3, FirstName: "f3", LastName: "l3", Sex: "male"},
+		&modeltest.UserInfo{UID: 4, FirstName: "f4", LastName: "l4", Sex: "female"},
+		&modeltest.UserInfo{UID: 5, FirstName: "f5", LastName: "l5", Sex: "male"},
+	}))
+
+	// 所有数据
+	us := []*modeltest.UserInfo{}
+	total, err := db.Find(&modeltest.UserInfo{}, &us, nil)
+	a.NotError(err).Equal(total, 5).Equal(len(us), 5)
+
+	// 未指定 Count 时，返回实际读取的记录数量
+	us = []*modeltest.UserInfo{}
+	total, err = db.Find(&modeltest.UserInfo{Sex: "male"}, &us, &orm.FindOptions{Limit: 2})
+	a.NotError(err).Equal(total, 2).Equal(len(us), 2)
+
+	// 条件、排序和分页
+	us = []*modeltest.UserInfo{}
+	total, err = db.Find(&modeltest.UserInfo{Sex: "male"}, &us, &orm.FindOptions{
+		Order:  []string{"-uid"},
+		Limit:  2,
+		Offset: 1,
+		Count:  true,
+	})
+	a.NotError(err).Equal(total, 3).Equal(len(us), 2)
+	a.Equal(us[0].UID, 3).Equal(us[1].UID, 2)
+
+	// 不存在的列名
+	us = []*modeltest.UserInfo{}
+	_, err = db.Find(&modeltest.UserInfo{}, &us, &orm.FindOptions{Order: []string{"not-exists"}})
+	a.Error(err)
+
+	// 事务中查询
+	tx, err := db.Begin()
+	a.NotError(err)
+	us = []*modeltest.UserInfo{}
+	total, err = tx.Find(&modeltest.UserInfo{Sex: "female"}, &us, &orm.FindOptions{Order: []string{"uid"}, Count: true})
+	a.NotError(err).Equal(total, 2).Equal(len(us), 2)
+	a.Equal(us[0].UID, 1).Equal(us[1].UID, 4)
+	a.NotError(tx.Commit())
+}
+
+func TestDB_SQL_cursor(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	a.NotError(db.InsertMany([]*modeltest.UserInfo{
+		&modeltest.UserInfo{UID: 3, FirstName: "f3", LastName: "l3", Sex: "male"},
+		&modeltest.UserInfo{UID: 4, FirstName: "f4", LastName: "l4", Sex: "female"},
+		&modeltest.UserInfo{UID: 5, FirstName: "f5", LastName: "l5", Sex: "male"},
+	}))
+
+	page := func(after, before *sqlbuilder.Cursor) ([]int, *sqlbuilder.Cursor, *sqlbuilder.Cursor) {
+		stmt := db.SQL().Select().Select("*").From("{#user_info}").Asc("{uid}").Limit(2)
+		if after != nil {
+			stmt.After(after)
+		} else if before != nil {
+			stmt.Before(before)
+		}
+
+		us := []*modeltest.UserInfo{}
+		cnt, prev, next, err := stmt.QueryObj(&us)
+		a.NotError(err).Equal(cnt, len(us))
+
+		ids := make([]int, 0, len(us))
+		for _, u := range us {
+			ids = append(ids, u.UID)
+		}
+		return ids, prev, next
+	}
+
+	ids, _, next := page(nil, nil)
+	a.Equal(ids, []int{1, 2})
+	a.Equal(next.Values(), []interface{}{2}) // 与字段 UID 的类型相同
+
+	// 游标可以编码之后再还原
+	token, err := next.Encode()
+	a.NotError(err)
+	next, err = sqlbuilder.ParseCursor(token)
+	a.NotError(err)
+
+	ids, _, next = page(next, nil)
+	a.Equal(ids, []int{3, 4})
+
+	ids, prev, next := page(next, nil)
+	a.Equal(ids, []int{5})
+
+	ids, prev, _ = page(nil, prev)
+	a.Equal(ids, []int{3, 4})
+
+	ids, _, _ = page(nil, prev)
+	a.Equal(ids, []int{1, 2})
+
+	// 没有更多的数据
+	ids, prev, next = page(next, nil)
+	a.Empty(ids).Nil(prev).Nil(next)
+}
+
+func TestDB_Find_hooks(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer func() {
+		a.NotError(db.Drop(&hookUser{}))
+		clearData(db, a)
+	}()
+	a.NotError(db.Create(&hookUser{}))
+	a.NotError(db.InsertMany([]*hookUser{&hookUser{Name: "u1"}, &hookUser{Name: "u2"}}))
+
+	us := []*hookUser{}
+	_, err := db.Find(&hookUser{}, &us, nil)
+	a.NotError(err).Equal(len(us), 2)
+	a.Equal(us[0].events, []string{"AfterFind"}).
+		Equal(us[1].events, []string{"AfterFind"})
+}
+
+func TestDB_softDelete(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer func() {
+		a.NotError(db.Drop(&softUser{}))
+		clearData(db, a)
+	}()
+	a.NotError(db.Create(&softUser{}))
+
+	_, err := db.Insert(&softUser{Name: "u1"})
+	a.NotError(err)
+	_, err = db.Insert(&softUser{Name: "u2"})
+	a.NotError(err)
+
+	// 软删除，数据依然存在
+	u1 := &softUser{ID: 1}
+	r, err := db.Delete(u1)
+	a.NotError(err).NotNil(r)
+	a.False(u1.Deleted.IsZero())
+	hasCount(db, a, "soft_users", 2)
+
+	// 重复删除，不会再次更新
+	r, err = db.Delete(&softUser{ID: 1})
+	a.NotError(err)
+	cnt, err := r.RowsAffected()
+	a.NotError(err).Equal(cnt, 0)
+
+	// Select 和 Count 过滤掉已经删除的数据
+	u1 = &softUser{ID: 1}
+	a.NotError(db.Select(u1))
+	a.Empty(u1.Name)
+	cnt, err = db.Count(&softUser{Name: "u1"})
+	a.NotError(err).Equal(cnt, 0)
+
+	// Unscoped
+	ctx := orm.Unscoped(context.Background())
+	u1 = &softUser{ID: 1}
+	a.NotError(db.SelectContext(ctx, u1))
+	a.Equal(u1.Name, "u1").False(u1.Deleted.IsZero())
+	cnt, err = db.CountContext(ctx, &softUser{Name: "u1"})
+	a.NotError(err).Equal(cnt, 1)
+
+	// SQL().SelectFrom()
+	stmt, err := db.SQL().SelectFrom(&softUser{})
+	a.NotError(err)
+	cnt, err = stmt.Count("COUNT(*) AS cnt").QueryInt("cnt")
+	a.NotError(err).Equal(cnt, 1)
+
+	// HardDelete
+	_, err = db.HardDelete(&softUser{ID: 1})
+	a.NotError(err)
+	hasCount(db, a, "soft_users", 1)
+
+	tx, err := db.Begin()
+	a.NotError(err)
+	_, err = tx.HardDelete(&softUser{ID: 2})
+	a.NotError(err)
+	a.NotError(tx.Commit())
+	hasCount(db, a, "soft_users", 0)
+}
+
+func TestDB_Update_occ(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer func() {
+		a.NotError(db.Drop(&occUser{}))
+		clearData(db, a)
+	}()
+	a.NotError(db.Create(&occUser{}))
+
+	_, err := db.Insert(&occUser{Name: "u1"})
+	a.NotError(err)
+
+	u1 := &occUser{ID: 1}
+	a.NotError(db.Select(u1))
+	a.Equal(u1.Version, 0)
+	u2 := &occUser{ID: 1}
+	a.NotError(db.Select(u2))
+
+	// 更新成功，版本号加 1
+	u1.Name = "u11"
+	_, err = db.Update(u1)
+	a.NotError(err)
+	a.Equal(u1.Version, 1)
+
+	// u2 的版本号已经过期
+	u2.Name = "u12"
+	r, err := db.Update(u2)
+	a.Equal(err, orm.ErrOCCConflict).Nil(r)
+	a.Equal(u2.Version, 0)
+
+	// 重新获取之后再更新
+	a.NotError(db.Select(u2))
+	a.Equal(u2.Version, 1)
+	u2.Name = "u12"
+	tx, err := db.Begin()
+	a.NotError(err)
+	_, err = tx.Update(u2)
+	a.NotError(err)
+	a.NotError(tx.Commit())
+	a.Equal(u2.Version, 2)
+
+	u1 = &occUser{ID: 1}
+	a.NotError(db.Select(u1))
+	a.Equal(u1.Name, "u12").Equal(u1.Version, 2)
+}
+
+func TestDB_timestamp(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer func() {
+		a.NotError(db.Drop(&timeUser{}))
+		clearData(db, a)
+	}()
+	a.NotError(db.Create(&timeUser{}))
+
+	now := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	db.SetClock(func() time.Time { return now })
+
+	u1 := &timeUser{Name: "u1"}
+	_, err := db.Insert(u1)
+	a.NotError(err)
+	a.True(u1.Created.Equal(now)).Equal(u1.Updated, now.Unix())
+
+	// 手动指定的值不会被覆盖
+	created := now.Add(-time.Hour)
+	_, err = db.Insert(&timeUser{Name: "u2", Created: created})
+	a.NotError(err)
+	u2 := &timeUser{ID: 2}
+	a.NotError(db.Select(u2))
+	a.True(u2.Created.Equal(created)).Equal(u2.Updated, now.Unix())
+
+	// 未指定 updated 列，依然会被更新
+	now = now.Add(time.Hour)
+	u1 = &timeUser{ID: 1, Name: "u11"}
+	_, err = db.Update(u1)
+	a.NotError(err)
+	a.Equal(u1.Updated, now.Unix())
+	u1 = &timeUser{ID: 1}
+	a.NotError(db.Select(u1))
+	a.Equal(u1.Name, "u11").
+		Equal(u1.Updated, now.Unix()).
+		True(u1.Created.Equal(now.Add(-time.Hour)))
+
+	// InsertMany
+	us := []*timeUser{&timeUser{Name: "u3"}, &timeUser{Name: "u4"}}
+	a.NotError(db.InsertMany(us))
+	a.True(us[0].Created.Equal(now)).Equal(us[1].Updated, now.Unix())
+	u4 := &timeUser{ID: 4}
+	a.NotError(db.Select(u4))
+	a.True(u4.Created.Equal(now))
+
+	// Upsert 不会更新创建时间
+	now = now.Add(time.Hour)
+	_, err = db.Upsert(&timeUser{ID: 4, Name: "u44"})
+	a.NotError(err)
+	u4 = &timeUser{ID: 4}
+	a.NotError(db.Select(u4))
+	a.Equal(u4.Name, "u44").
+		Equal(u4.Updated, now.Unix()).
+		True(u4.Created.Equal(now.Add(-time.Hour)))
+}
+
+func TestDB_Upsert(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	// 主键冲突，更新所有非主键列
+	_, err := db.Upsert(&modeltest.UserInfo{UID: 1, FirstName: "f11", LastName: "l11", Sex: "male"})
+	a.NotError(err)
+	u1 := &modeltest.UserInfo{UID: 1}
+	a.NotError(db.Select(u1))
+	a.Equal(u1, &modeltest.UserInfo{UID: 1, FirstName: "f11", LastName: "l11", Sex: "male"})
+
+	// 主键冲突，仅更新指定的列
+	_, err = db.Upsert(&modeltest.UserInfo{UID: 1, FirstName: "f12", LastName: "l12", Sex: "female"}, "sex")
+	a.NotError(err)
+	u1 = &modeltest.UserInfo{UID: 1}
+	a.NotError(db.Select(u1))
+	a.Equal(u1, &modeltest.UserInfo{UID: 1, FirstName: "f11", LastName: "l11", Sex: "female"})
+
+	// 不冲突，插入数据
+	_, err = db.Upsert(&modeltest.UserInfo{UID: 3, FirstName: "f3", LastName: "l3"})
+	a.NotError(err)
+	hasCount(db, a, "user_info", 3)
+
+	// 唯一约束冲突
+	_, err = db.Upsert(&modeltest.Admin{Email: "email1", Group: 1, User: modeltest.User{Username: "username1", Password: "password2"}}, "password")
+	a.NotError(err)
+	hasCount(db, a, "administrators", 1)
+	a1 := &modeltest.Admin{Email: "email1"}
+	a.NotError(db.Select(a1))
+	a.Equal(a1.Password, "password2").Equal(a1.ID, 1)
+
+	// 没有主键和唯一约束的值
+	r, err := db.Upsert(&modeltest.UserInfo{Sex: "male"})
+	a.Error(err).Nil(r)
+}
+
+func TestDB_InsertMany(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	// 占位符数量超过 sqlite3 的限制，会被拆分成多条语句
+	us := make([]*modeltest.UserInfo, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		us = append(us, &modeltest.UserInfo{
+			UID:       i + 100,
+			FirstName: "fm" + strconv.Itoa(i),
+			LastName:  "lm" + strconv.Itoa(i),
+			Sex:       "female",
+		})
+	}
+	prepared := 0
+	var inserted int64
+	db.AddInterceptor(orm.InterceptorFunc(func(ctx context.Context, e *orm.Event) {
+		switch {
+		case e.Type == orm.EventPrepare:
+			prepared++
+		case e.Type == orm.EventExec && e.Tx && e.Err == nil && strings.HasPrefix(e.Query, "INSERT"):
+			inserted += e.RowsAffected
+		}
+	}))
+	a.NotError(db.InsertMany(us))
+	hasCount(db, a, "user_info", 1002)
+	a.Equal(prepared, 1)    // 多条语句共用同一个预编译语句
+	a.Equal(inserted, 1000) // 通过预编译语句执行时，也会触发拦截器
+
+	u := &modeltest.UserInfo{UID: 1099}
+	a.NotError(db.Select(u))
+	a.Equal(u, &modeltest.UserInfo{UID: 1099, FirstName: "fm999", LastName: "lm999", Sex: "female"})
+
+	// 最后一条语句主键冲突，所有数据都被回滚
+	us = make([]*modeltest.UserInfo, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		us = append(us, &modeltest.UserInfo{
+			UID:       i + 2000,
+			FirstName: "ff" + strconv.Itoa(i),
+			LastName:  "ll" + strconv.Itoa(i),
+		})
+	}
+	us[999].UID = 1
+	a.Error(db.InsertMany(us))
+	hasCount(db, a, "user_info", 1002)
+}
+
+func TestDB_Insert_ai(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	admin := &modeltest.Admin{Email: "email2", Group: 1, User: modeltest.User{Username: "username2"}}
+	r, err := db.Insert(admin)
+	a.NotError(err)
+	a.Equal(admin.ID, 2)
+	id, err := r.LastInsertId()
+	a.NotError(err).Equal(id, 2)
+	cnt, err := r.RowsAffected()
+	a.NotError(err).Equal(cnt, 1)
+
+	group := &modeltest.Group{Name: "group2"}
+	_, err = db.Insert(group)
+	a.NotError(err)
+	a.Equal(group.ID, 2)
+
+	// 非指针，无法回写
+	_, err = db.Insert(modeltest.Group{Name: "group3"})
+	a.NotError(err)
+	hasCount(db, a, "groups", 3)
+}
+
+func TestDB_InsertMany_ai(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	initData(db, a)
+	defer clearData(db, a)
+
+	// 超过单条语句的行数，且中间有手动指定自增列的元素
+	gs := make([]*modeltest.Group, 0, 700)
+	for i := 0; i < 700; i++ {
+		gs = append(gs, &modeltest.Group{Name: "g" + strconv.Itoa(i)})
+	}
+	gs[600].ID = 5000
+	a.NotError(db.InsertMany(gs))
+	hasCount(db, a, "groups", 701)
+
+	a.Equal(gs[0].ID, 2).
+		Equal(gs[599].ID, 601).
+		Equal(gs[600].ID, 5000).
+		Equal(gs[601].ID, 5001).
+		Equal(gs[699].ID, 5099)
+	for _, g := range gs {
+		sel := &modeltest.Group{ID: g.ID}
+		a.NotError(db.Select(sel))
+		a.Equal(sel.Name, g.Name)
+	}
+
+	// 值类型的数组
+	vs := []modeltest.Group{{Name: "v1"}, {Name: "v2"}}
+	a.NotError(db.InsertMany(vs))
+	a.Equal(vs[0].ID, 5100).Equal(vs[1].ID, 5101)
+}
+
+func TestDB_Preload(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer func() {
+		for _, v := range []interface{}{&relPostTag{}, &relTag{}, &relComment{}, &relPost{}, &relUser{}} {
+			a.NotError(db.Drop(v))
+		}
+		clearData(db, a)
+	}()
+	for _, v := range []interface{}{&relUser{}, &relPost{}, &relComment{}, &relTag{}, &relPostTag{}} {
+		a.NotError(db.Create(v))
+	}
+
+	a.NotError(db.InsertMany([]*relUser{{Name: "u1"}, {Name: "u2"}, {Name: "u3"}}))
+	a.NotError(db.InsertMany([]*relPost{
+		{UID: 1, Title: "p1"},
+		{UID: 1, Title: "p2"},
+		{UID: 2, Title: "p3"},
+	}))
+	a.NotError(db.InsertMany([]*relComment{
+		{PID: 1, Content: "c1"},
+		{PID: 1, Content: "c2"},
+		{PID: 3, Content: "c3"},
+	}))
+	a.NotError(db.InsertMany([]*relTag{{Name: "t1"}, {Name: "t2"}}))
+	a.NotError(db.InsertMany([]*relPostTag{
+		{PID: 1, TID: 1},
+		{PID: 1, TID: 2},
+		{PID: 2, TID: 2},
+	}))
+
+	// has many 和嵌套的 has many
+	us := []*relUser{}
+	_, err := db.Find(&relUser{}, &us, &orm.FindOptions{
+		Order:   []string{"id"},
+		Preload: []string{"Posts.Comments"},
+	})
+	a.NotError(err).Equal(len(us), 3)
+	a.Equal(len(us[0].Posts), 2).Equal(len(us[1].Posts), 1).Equal(len(us[2].Posts), 0)
+	a.Equal(us[0].Posts[0].Title, "p1").Equal(len(us[0].Posts[0].Comments), 2)
+	a.Equal(len(us[0].Posts[1].Comments), 0)
+	a.Equal(us[1].Posts[0].Comments[0].Content, "c3")
+
+	// belongs to 和 many to many
+	ps := []*relPost{}
+	_, err = db.Find(&relPost{}, &ps, &orm.FindOptions{Order: []string{"id"}})
+	a.NotError(err).Equal(len(ps), 3)
+	a.NotError(db.Preload(&ps, "Author", "Tags"))
+	a.Equal(ps[0].Author.Name, "u1").Equal(ps[2].Author.Name, "u2")
+	a.Equal(len(ps[0].Tags), 2).Equal(len(ps[1].Tags), 1).Equal(len(ps[2].Tags), 0)
+	a.Equal(ps[1].Tags[0].Name, "t2")
+
+	// 单个对象
+	u := &relUser{ID: 2}
+	a.NotError(db.Select(u))
+	a.NotError(db.Preload(u, "Posts"))
+	a.Equal(len(u.Posts), 1).Equal(u.Posts[0].Title, "p3")
+
+	// 不存在的关联关系
+	a.Error(db.Preload(u, "not-exists"))
+
+	// 非指针
+	a.Error(db.Preload(relUser{ID: 1}, "Posts"))
+
+	// 外键引用的列不存在，或是引用的表与关联的模型不同
+	bad := &badRefPost{ID: 1, UID: 1, TID: 1}
+	a.Error(db.Preload(bad, "Author"))
+	a.Error(db.Preload(bad, "Tag"))
+}
+
+func TestDB_Update_tracker(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer func() {
+		a.NotError(db.Drop(&trackedUser{}))
+		clearData(db, a)
+	}()
+	a.NotError(db.Create(&trackedUser{}))
+
+	_, err := db.Insert(&trackedUser{Name: "u1", Age: 5})
+	a.NotError(err)
+
+	// 未读取过的对象，不存在快照，依然忽略零值
+	u := &trackedUser{ID: 1, Name: "u11"}
+	a.Nil(u.Snapshot())
+	_, err = db.Update(u)
+	a.NotError(err)
+	a.Nil(u.Snapshot())
+
+	// 读取之后，修改为零值也会被更新
+	u = &trackedUser{ID: 1}
+	a.NotError(db.Select(u))
+	a.NotNil(u.Snapshot()).Equal(u.Name, "u11").Equal(u.Age, 5)
+	u.Age = 0
+	_, err = db.Update(u)
+	a.NotError(err)
+
+	u2 := &trackedUser{ID: 1}
+	a.NotError(db.Select(u2))
+	a.Equal(u2.Age, 0).Equal(u2.Name, "u11")
+
+	// 更新之后快照也被更新，没有变化的内容
+	r, err := db.Update(u)
+	a.Equal(err, orm.ErrNoChanges).Nil(r)
+
+	// 仅更新有变化的列，不会覆盖其它操作的修改
+	u2.Name = "u12"
+	_, err = db.Update(u2)
+	a.NotError(err)
+	u.Age = 6
+	_, err = db.Update(u)
+	a.NotError(err)
+	a.NotError(db.Select(u))
+	a.Equal(u.Name, "u12").Equal(u.Age, 6)
+
+	// 指定的列，即使没有变化也会更新
+	_, err = db.Update(u, "name")
+	a.NotError(err)
+}
@@ -7,6 +7,7 @@ package dialect
 
 import (
 	"database/sql"
+	"errors"
 	"reflect"
 	"time"
 
@@ -190,6 +191,58 @@ func mysqlLimitSQL(limit interface{}, offset ...interface{}) (string, []interfac
 	return query + " ", []interface{}{limit, offset[0]}
 }
 
+// mysql 的 ON DUPLICATE KEY UPDATE 语法，冲突的检测由数据库根据主键和唯一约束决定，
+// 所以会忽略 target 参数。
+func mysqlUpsertSQL(target, update []string) (string, error) {
+	if len(update) == 0 { // 没有需要更新的列，则更新冲突列为其自身的值
+		if len(target) == 0 {
+			return "", errors.New("未指定需要更新的列")
+		}
+		update = target[:1]
+	}
+
+	buf := sqlbuilder.New(" ON DUPLICATE KEY UPDATE ")
+	for _, col := range update {
+		buf.WriteString(col).
+			WriteString("=VALUES(").
+			WriteString(col).
+			WriteString("),")
+	}
+	buf.TruncateLast(1)
+
+	return buf.String(), nil
+}
+
+// postgres 的 ON CONFLICT 语法的实现。支持以下数据库：
+// Postgres 9.5+, SQLite3 3.24+
+func postgresUpsertSQL(target, update []string) (string, error) {
+	if len(target) == 0 {
+		return "", errors.New("未指定冲突检测的列")
+	}
+
+	buf := sqlbuilder.New(" ON CONFLICT(")
+	for _, col := range target {
+		buf.WriteString(col).WriteByte(',')
+	}
+	buf.TruncateLast(1).WriteByte(')')
+
+	if len(update) == 0 {
+		buf.WriteString(" DO NOTHING")
+		return buf.String(), nil
+	}
+
+	buf.WriteString(" DO UPDATE SET ")
+	for _, col := range update {
+		buf.WriteString(col).
+			WriteString("=EXCLUDED.").
+			WriteString(col).
+			WriteByte(',')
+	}
+	buf.TruncateLast(1)
+
+	return buf.String(), nil
+}
+
 // oracle系列数据库分页语法的实现。支持以下数据库：
 // Derby, SQL Server 2012, Oracle 12c, the SQL 2008 standard
 func oracleLimitSQL(limit interface{}, offset ...interface{}) (string, []interface{}) {
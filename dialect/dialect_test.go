@@ -136,3 +136,36 @@ func TestOracleLimitSQL(t *testing.T) {
 	a.Equal(ret, []interface{}{2, sql.Named("limit", 1)})
 	sqltest.Equal(a, query, "offset ? rows fetch next @limit rows only")
 }
+
+func TestMysqlUpsertSQL(t *testing.T) {
+	a := assert.New(t)
+
+	query, err := mysqlUpsertSQL([]string{"id"}, []string{"c1", "c2"})
+	a.NotError(err)
+	sqltest.Equal(a, query, " ON DUPLICATE KEY UPDATE c1=VALUES(c1),c2=VALUES(c2)")
+
+	// 未指定更新列
+	query, err = mysqlUpsertSQL([]string{"id"}, nil)
+	a.NotError(err)
+	sqltest.Equal(a, query, " ON DUPLICATE KEY UPDATE id=VALUES(id)")
+
+	query, err = mysqlUpsertSQL(nil, nil)
+	a.Error(err).Empty(query)
+}
+
+func TestPostgresUpsertSQL(t *testing.T) {
+	a := assert.New(t)
+
+	query, err := postgresUpsertSQL([]string{"k1", "k2"}, []string{"c1", "c2"})
+	a.NotError(err)
+	sqltest.Equal(a, query, " ON CONFLICT(k1,k2) DO UPDATE SET c1=EXCLUDED.c1,c2=EXCLUDED.c2")
+
+	// 未指定更新列
+	query, err = postgresUpsertSQL([]string{"id"}, nil)
+	a.NotError(err)
+	sqltest.Equal(a, query, " ON CONFLICT(id) DO NOTHING")
+
+	// 未指定冲突列
+	query, err = postgresUpsertSQL(nil, []string{"c1"})
+	a.Error(err).Empty(query)
+}
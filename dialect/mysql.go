@@ -36,8 +36,9 @@ func (m *mysql) QuoteTuple() (byte, byte) {
 	return '`', '`'
 }
 
-func (m *mysql) SQL(sql string) (string, error) {
-	return sql, nil
+// 将 @name 占位符转换成 ? 的形式，并按顺序重新排列参数。
+func (m *mysql) SQL(query string, args []interface{}) (string, []interface{}, error) {
+	return replacePlaceholders(query, args, m.BackslashEscapes(), false)
 }
 
 func (m *mysql) CreateTableSQL(model *model.Model) ([]string, error) {
@@ -125,10 +126,36 @@ func (m *mysql) LimitSQL(limit interface{}, offset ...interface{}) (string, []in
 	return mysqlLimitSQL(limit, offset...)
 }
 
+func (m *mysql) UpsertSQL(target, update []string) (string, error) {
+	return mysqlUpsertSQL(target, update)
+}
+
+func (m *mysql) LastInsertIDSQL(table, col string) (sql string, append bool) {
+	return "", false
+}
+
+// mysql 虽然支持行值比较，但是无法有效利用索引。
+func (m *mysql) RowValueComparison() bool {
+	return false
+}
+
 func (m *mysql) TruncateTableSQL(table, ai string) string {
 	return "TRUNCATE TABLE " + table
 }
 
+func (m *mysql) MaxPlaceholders() int {
+	return 65535
+}
+
+func (m *mysql) BackslashEscapes() bool {
+	return true
+}
+
+// LAST_INSERT_ID() 返回的是第一行数据的自增列值。
+func (m *mysql) FirstInsertID() bool {
+	return true
+}
+
 func (m *mysql) TransactionalDDL() bool {
 	return false
 }
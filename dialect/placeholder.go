@@ -0,0 +1,112 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package dialect
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/issue9/orm/internal/lexer"
+)
+
+// 将 query 中的 @name 命名参数替换成当前数据库的占位符，
+// 并按占位符在语句中出现的顺序重新排列 args，同一命名参数可以出现多次。
+//
+// 仅 args 中存在同名的 sql.NamedArg 时，才会替换 @name，
+// 所以 mysql 的 @var 等变量不受影响；未被引用的命名参数会被忽略。
+//
+// dollar 表示以 postgres 的 $N 作为占位符，此时 ? 也会被转换成 $N，
+// ?? 表示 ? 字符本身，语句中已有的 $N 保持不变，但不能与其它占位符同时使用。
+//
+// 字符串、引号包含的标识符以及注释中的内容保持不变，
+// backslash 表示字符串中的反斜杠是否为转义字符。
+func replacePlaceholders(query string, args []interface{}, backslash, dollar bool) (string, []interface{}, error) {
+	named := make(map[string]interface{}, len(args))
+	positional := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		n, ok := arg.(sql.NamedArg)
+		switch {
+		case ok && n.Name != "":
+			named[n.Name] = n.Value
+		case ok:
+			positional = append(positional, n.Value)
+		default:
+			positional = append(positional, arg)
+		}
+	}
+
+	if len(named) == 0 && (!dollar || strings.IndexByte(query, '?') < 0) {
+		return query, args, nil
+	}
+
+	ret := make([]byte, 0, len(query)+10)
+	newArgs := make([]interface{}, 0, len(args))
+	num := 0 // 已经生成的占位符数量
+	next := 0
+	hasDollar := false
+
+	placeholder := func() {
+		num++
+		if dollar {
+			ret = append(ret, '$')
+			ret = strconv.AppendInt(ret, int64(num), 10)
+		} else {
+			ret = append(ret, '?')
+		}
+	}
+
+	for _, token := range lexer.Split(query, backslash) {
+		if token.Kind != lexer.Code {
+			ret = append(ret, token.Text...)
+			continue
+		}
+
+		text := token.Text
+		for i := 0; i < len(text); i++ {
+			c := text[i]
+			switch {
+			case c == '?' && dollar && i+1 < len(text) && text[i+1] == '?': // 转义的 ?
+				ret = append(ret, '?')
+				i++
+			case c == '?':
+				placeholder()
+				if next < len(positional) {
+					newArgs = append(newArgs, positional[next])
+					next++
+				}
+			case c == '@' && len(named) > 0 && (i == 0 || (!lexer.IsWord(text[i-1]) && text[i-1] != '@')):
+				end := i + 1
+				for end < len(text) && lexer.IsWord(text[end]) {
+					end++
+				}
+
+				v, found := named[text[i+1:end]]
+				if !found {
+					ret = append(ret, c)
+					continue
+				}
+				placeholder()
+				newArgs = append(newArgs, v)
+				i = end - 1
+			case c == '$' && dollar && i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9':
+				hasDollar = true
+				ret = append(ret, c)
+			default:
+				ret = append(ret, c)
+			}
+		}
+	}
+
+	if hasDollar && num > 0 {
+		return "", nil, errors.New("不能同时使用 $N 与其它形式的占位符")
+	}
+
+	if len(named) == 0 { // 仅转换了 ? 占位符，参数保持不变
+		return string(ret), args, nil
+	}
+	return string(ret), newArgs, nil
+}
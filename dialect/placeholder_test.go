@@ -0,0 +1,47 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package dialect
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestReplacePlaceholders(t *testing.T) {
+	a := assert.New(t)
+
+	eq := func(query string, args []interface{}, backslash, dollar bool, q string, as []interface{}) {
+		ret, retArgs, err := replacePlaceholders(query, args, backslash, dollar)
+		a.NotError(err)
+		a.Equal(ret, q).Equal(retArgs, as)
+	}
+
+	// 没有命名参数，保持不变
+	args := []interface{}{1, 2}
+	eq("a=? AND b=?", args, false, false, "a=? AND b=?", args)
+	eq("a=@id", args, false, false, "a=@id", args)
+
+	// 按占位符的顺序重新排列
+	eq("a=@id AND b=? AND c=@name AND d=@id",
+		[]interface{}{sql.Named("name", "n"), 2, sql.Named("id", 1)}, false, false,
+		"a=? AND b=? AND c=? AND d=?", []interface{}{1, 2, "n", 1})
+
+	// 未命名的 sql.NamedArg 作为普通参数
+	eq("a=? AND b=@id", []interface{}{sql.Named("", 2), sql.Named("id", 1)}, false, false,
+		"a=? AND b=?", []interface{}{2, 1})
+
+	// 字符串和注释中的内容，mysql 的变量，以及不存在的参数名保持不变
+	eq("a=@id AND b='@id' /* @id */ AND c=@@version AND d=@x AND e=x@id",
+		[]interface{}{sql.Named("id", 1)}, false, false,
+		"a=? AND b='@id' /* @id */ AND c=@@version AND d=@x AND e=x@id", []interface{}{1})
+	eq(`a=@id AND b='\'@id' AND c=@id`, []interface{}{sql.Named("id", 1)}, true, false,
+		`a=? AND b='\'@id' AND c=?`, []interface{}{1, 1})
+
+	// $N
+	eq("a=@id AND b=? AND c ?? 'k'", []interface{}{2, sql.Named("id", 1)}, false, true,
+		"a=$1 AND b=$2 AND c ? 'k'", []interface{}{1, 2})
+}
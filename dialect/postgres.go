@@ -8,8 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strconv"
-	"strings"
 
 	"github.com/issue9/orm"
 	"github.com/issue9/orm/model"
@@ -33,28 +31,13 @@ func (p *postgres) QuoteTuple() (byte, byte) {
 	return '"', '"'
 }
 
-// 在有 ? 占位符的情况下，语句中不能包含$字符串
-func (p *postgres) SQL(sql string) (string, error) {
-	if strings.IndexByte(sql, '?') < 0 {
-		return sql, nil
-	}
-
-	num := 1
-	ret := make([]rune, 0, len(sql))
-	for _, c := range sql {
-		switch c {
-		case '?':
-			ret = append(ret, '$')
-			ret = append(ret, []rune(strconv.Itoa(num))...)
-			num++
-		case '$':
-			return "", errors.New("语句中包含非法的字符串:$")
-		default:
-			ret = append(ret, c)
-		}
-	}
-
-	return string(ret), nil
+// 将 ? 和 @name 占位符转换成 $N 的形式。
+//
+// 字符串、引号包含的标识符、注释以及 $tag$ 包含的内容不会被转换；
+// 语句中已有的 $N 也保持不变，但不能与其它占位符同时使用；
+// JSONB 的 ?、?| 和 ?& 操作符需要写成 ??、??| 和 ??&。
+func (p *postgres) SQL(query string, args []interface{}) (string, []interface{}, error) {
+	return replacePlaceholders(query, args, p.BackslashEscapes(), true)
 }
 
 func (p *postgres) CreateTableSQL(model *model.Model) ([]string, error) {
@@ -91,6 +74,19 @@ func (p *postgres) LimitSQL(limit interface{}, offset ...interface{}) (string, [
 	return mysqlLimitSQL(limit, offset...)
 }
 
+func (p *postgres) UpsertSQL(target, update []string) (string, error) {
+	return postgresUpsertSQL(target, update)
+}
+
+// lib/pq 不支持 sql.Result.LastInsertId()，通过 RETURNING 子句获取。
+func (p *postgres) LastInsertIDSQL(table, col string) (sql string, append bool) {
+	return " RETURNING " + col, true
+}
+
+func (p *postgres) RowValueComparison() bool {
+	return true
+}
+
 func (p *postgres) TruncateTableSQL(table, ai string) string {
 	w := sqlbuilder.New("TRUNCATE TABLE ").WriteString(table)
 
@@ -101,6 +97,20 @@ func (p *postgres) TruncateTableSQL(table, ai string) string {
 	return w.String()
 }
 
+func (p *postgres) MaxPlaceholders() int {
+	return 65535
+}
+
+// 仅 E'...' 形式的字符串中，反斜杠才是转义字符。
+func (p *postgres) BackslashEscapes() bool {
+	return false
+}
+
+// 自增列的值通过 RETURNING 子句获取，不会用到此值。
+func (p *postgres) FirstInsertID() bool {
+	return false
+}
+
 func (p *postgres) TransactionalDDL() bool {
 	return true
 }
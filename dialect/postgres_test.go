@@ -63,13 +63,13 @@ func TestPostgres_SQL(t *testing.T) {
 	a.NotNil(p)
 
 	eq := func(s1, s2 string) {
-		ret, err := p.SQL(s1)
-		a.NotError(err)
+		ret, args, err := p.SQL(s1, nil)
+		a.NotError(err).Nil(args)
 		a.Equal(ret, s2)
 	}
 
 	err := func(s1 string) {
-		ret, err := p.SQL(s1)
+		ret, _, err := p.SQL(s1, nil)
 		a.Error(err).Empty(ret)
 	}
 
@@ -80,10 +80,37 @@ func TestPostgres_SQL(t *testing.T) {
 	eq("abc?abc?def", "abc$1abc$2def")
 	eq("中文?abc?def", "中文$1abc$2def")
 
-	err("$a?bc")
-	err("?$abc$")
-	err("a?bc$abc$abc")
-	err("?中$文")
+	eq("$a?bc", "$a$1bc")
+	eq("?中$文", "$1中$文")
+
+	// 字符串、标识符、注释和 $tag$ 中的 ? 不转换
+	eq("a=? AND b='?' AND \"c?\"=? -- ?\n/* ? */", "a=$1 AND b='?' AND \"c?\"=$2 -- ?\n/* ? */")
+	eq("a=? AND b=$$it's ?$$ AND c=$fn$?$fn$ AND d=?", "a=$1 AND b=$$it's ?$$ AND c=$fn$?$fn$ AND d=$2")
+	eq("a=E'\\'?' AND b=?", "a=E'\\'?' AND b=$1")
+
+	// JSONB 操作符
+	eq("data ?? 'k' AND data ??| array['a'] AND id=?", "data ? 'k' AND data ?| array['a'] AND id=$1")
+
+	// 已有的 $N
+	eq("a=$1 AND b='?'", "a=$1 AND b='?'")
+	eq("a=$1 AND b ?? 'k'", "a=$1 AND b ? 'k'")
+	err("a=$1 AND b=?")
+
+	// 命名参数
+	ret, args, e := p.SQL("a=@id AND b=? AND c=@id AND d='@id'", []interface{}{sql.Named("id", 1), 2})
+	a.NotError(e)
+	a.Equal(ret, "a=$1 AND b=$2 AND c=$3 AND d='@id'").Equal(args, []interface{}{1, 2, 1})
+	_, _, e = p.SQL("a=$1 AND b=@id", []interface{}{sql.Named("id", 1)})
+	a.Error(e)
+}
+
+func TestPostgres_LastInsertIDSQL(t *testing.T) {
+	a := assert.New(t)
+	p := Postgres()
+
+	query, append := p.LastInsertIDSQL("tbl", "id")
+	a.True(append)
+	sqltest.Equal(a, query, " RETURNING id")
 }
 
 func BenchmarkPostgres_SQL(b *testing.B) {
@@ -94,6 +121,6 @@ func BenchmarkPostgres_SQL(b *testing.B) {
 	s1 := "SELECT * FROM tbl WHERE uid>? AND group=? AND username LIKE ?"
 
 	for i := 0; i < b.N; i++ {
-		p.SQL(s1)
+		p.SQL(s1, nil)
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/issue9/orm"
 	"github.com/issue9/orm/model"
@@ -34,8 +35,9 @@ func (s *sqlite3) QuoteTuple() (byte, byte) {
 	return '`', '`'
 }
 
-func (s *sqlite3) SQL(sql string) (string, error) {
-	return sql, nil
+// 将 @name 占位符转换成 ? 的形式，并按顺序重新排列参数。
+func (s *sqlite3) SQL(query string, args []interface{}) (string, []interface{}, error) {
+	return replacePlaceholders(query, args, s.BackslashEscapes(), false)
 }
 
 func (s *sqlite3) CreateTableSQL(model *model.Model) ([]string, error) {
@@ -104,15 +106,42 @@ func (s *sqlite3) LimitSQL(limit interface{}, offset ...interface{}) (string, []
 	return mysqlLimitSQL(limit, offset...)
 }
 
+func (s *sqlite3) UpsertSQL(target, update []string) (string, error) {
+	return postgresUpsertSQL(target, update)
+}
+
+func (s *sqlite3) LastInsertIDSQL(table, col string) (sql string, append bool) {
+	return "", false
+}
+
+// 3.15.0 之后的版本支持行值比较。
+func (s *sqlite3) RowValueComparison() bool {
+	return true
+}
+
 func (s *sqlite3) TruncateTableSQL(table, ai string) string {
 	return sqlbuilder.New("DELETE FROM ").
 		WriteString(table).
 		WriteString(";DELETE FROM SQLITE_SEQUENCE WHERE name='").
-		WriteString(table).
+		WriteString(strings.Trim(table, "{}")).
 		WriteString("';").
 		String()
 }
 
+// 3.32.0 之前的版本限制为 999，之后为 32766，取较小值以兼容旧版本。
+func (s *sqlite3) MaxPlaceholders() int {
+	return 999
+}
+
+func (s *sqlite3) BackslashEscapes() bool {
+	return false
+}
+
+// last_insert_rowid() 返回的是最后一行数据的自增列值。
+func (s *sqlite3) FirstInsertID() bool {
+	return false
+}
+
 func (s *sqlite3) TransactionalDDL() bool {
 	return true
 }
@@ -26,6 +26,12 @@
 //  // 另一个 DB 实例
 //  db2 := orm.NewDB("sqlite3", "./db2", "db2_", dialect.Sqlite3())
 //
+// 若存在主从数据库，可以通过 NewDBWithReplicas() 初始化，
+// 查询语句会在从数据库上执行，其它语句及事务则在主数据库上执行：
+//  db3 := orm.NewDBWithReplicas(primary, []*orm.Replica{{DB: replica1, Weight: 2}, {DB: replica2}}, "p_", dialect.Mysql())
+//  // 通过 WithPrimary() 强制在主数据库上查询
+//  db3.SelectContext(orm.WithPrimary(ctx), &User{ID: 1})
+//
 //
 //
 // 占位符
@@ -39,6 +45,15 @@
 //  select * from p_user where `group`=1
 // DB.Query(),DB.Exec(),DB.Prepare().DB.Where() 及 Tx 与之对应的函数都可以使用占位符。
 //
+// 字符串、引号包含的标识符以及注释中的内容不会被替换，
+// 若需要在语句中使用 # 字符本身，可以使用 ## 代替：
+//  select * from #user where {name}='a#b' -- #comment
+//  // 替换之后
+//  select * from p_user where `name`='a#b' -- #comment
+//
+// postgres 中的 ? 占位符会被转换成 $N 的形式，字符串等内容中的 ? 不受影响，
+// JSONB 的 ?、?| 和 ?& 操作符则需要写成 ??、??| 和 ??&。
+//
 // Model 不能指定占位符，它们默认总会使用占位符，且无法取消。
 //
 //
@@ -77,6 +92,7 @@
 //
 //  ai: 自增，若指定了自增列，则将自动取消其它的 pk 设置。无法指定起始值和步长。
 //  可手动设置一个非零值来更改某条数据的 AI 行为。
+//  插入数据之后，会将自增列的值写回到对象中。
 //
 //  unique(index_name): 唯一索引，支持联合索引，index_name 为约束名，
 //  会将 index_name 为一样的字段定义为一个联合索引。
@@ -84,6 +100,19 @@
 //  index(index_name): 普通的关键字索引，同 unique 一样会将名称相同的索引定义为一个联合索引。
 //
 // occ(true|false) 当前列作为乐观锁字段。
+// 更新数据时，若该列的值已经被其它操作修改，则返回 ErrOCCConflict，
+// 更新成功，则会将对象中该列的值加 1。
+//
+//  softdelete: 当前列作为软删除字段，类型只能是 time.Time，且必须同时指定 nullable。
+//  Delete() 会将该列设置为当前时间，而不是删除数据；Select() 和 Count()
+//  会过滤掉该列不为 NULL 的数据。可以通过 Unscoped() 或是 HardDelete() 忽略此设置。
+//
+//  created: 当前列作为创建时间，类型可以是 time.Time 或是表示 unix 时间戳的整数。
+//  插入数据时，若该列为零值，则填充为当前时间。
+//
+//  updated: 当前列作为更新时间，类型同 created。插入数据时与 created 相同，
+//  更新数据时，无论是否指定该列，都会更新为当前时间。
+//  当前时间默认由 time.Now 获取，可以通过 DB.SetClock() 修改。
 //
 //  default(value): 指定默认值。相当于定义表结构时的 DEFAULT。
 //  当一个字段如果是个零值(reflect.Zero())时，将会使用它的默认值，
@@ -94,6 +123,15 @@
 //  定义物理外键，最少需要指定 fk_name,refTabl,refColName 三个值。分别对应约束名，
 //  引用的表和引用的字段，updateRule,deleteRule，在不指定的情况下，使用数据库的默认值。
 //
+//  belongsto(fk_name)、hasone(fk_name)、hasmany(fk_name):
+//  声明与其它模型的关联关系，不能与其它属性同时使用，且该字段不会作为表中的列。
+//  belongsto 的 fk_name 为当前模型中定义的外键，hasone 和 hasmany 的 fk_name
+//  为关联模型中引用当前模型的外键。字段类型为结构体(指针)，hasmany 则为其切片。
+//
+//  manytomany(join_table,join_col,join_ref_col):
+//  通过中间表 join_table 声明的多对多关系，join_col 为引用当前模型主键的列，
+//  join_ref_col 为引用关联模型主键的列。字段类型为结构体(指针)的切片。
+//
 //  check(chk_name, expr): check 约束。chk_name 为约束名，expr 为该约束的表达式。
 //  check 约束只能在 model.Metaer 接口中指定，而不是像其它约束一样，通过字段的 struct tag 指定。
 //  因为 check 约束的表达式可以通过 and 或是 or 等符号连接多条基本表达式，
@@ -132,6 +170,12 @@
 //  // 将 id 为 1 的记录的 FirstName 更改为 abc；对象中的零值不会被提交。
 //  db.Update(&User{Id:1,FirstName:"abc"})
 //  sqlbuilder.Update(db).Table("#table").Where("id=?",1).Set("FirstName", "abc").Exec()
+//  // 嵌入了 orm.Tracked 的对象，通过 Select() 读取之后，
+//  // Update() 仅提交有变化的列，包括被修改为零值的列；没有变化时返回 ErrNoChanges。
+//  u := &TrackedUser{Id:1}
+//  err := db.Select(u)
+//  u.Age = 0
+//  _, err = db.Update(u)
 //
 // Delete:
 //  // 删除 id 为 1 的记录
@@ -143,13 +187,71 @@
 //  db.Insert(&User{Id:1,FirstName:"abc"})
 //  // 一次性插入多条数据
 //  tx.InsertMany(&User{Id:1,FirstName:"abc"},&User{Id:1,FirstName:"abc"})
+//  // 在事务中插入多条数据，数据量较大时会自动拆分成多条语句
+//  db.InsertMany([]*User{&User{Id:1,FirstName:"abc"},&User{Id:2,FirstName:"abc"}})
+//  // 插入数据，若主键或唯一约束冲突，则更新 first_name 列
+//  db.Upsert(&User{Id:1,FirstName:"abc"}, "first_name")
+//  // 将查询结果插入到另一张表中，即 INSERT INTO ... SELECT 语句
+//  sel := sqlbuilder.Select(e, e.Dialect()).Select("{id}", "{name}").From("{#user}").Where("{created}<?", t)
+//  _, err = sqlbuilder.Insert(e).Table("{#user_archive}").Columns("{id}", "{name}").Select(sel).Exec()
 //
 // Select:
 //  // 导出 id=1 的数据
-//  _,err := sqlbuilder.Select(e, e.Dialect()).Select("*").From("{#table}").Where("id=1").QueryObj(obj)
+//  _, _, _, err := sqlbuilder.Select(e, e.Dialect()).Select("*").From("{#table}").Where("id=1").QueryObj(obj)
 //  // 导出 id 为 1 的数据，并回填到 user 实例中
 //  user := &User{Id:1}
 //  err := e.Select(u)
+//  // 导出所有 first_name 为 abc 的数据，按 id 倒序，取前 10 条，同时返回总数
+//  users := []*User{}
+//  total, err := e.Find(&User{FirstName:"abc"}, &users, &orm.FindOptions{
+//      Order: []string{"-id"},
+//      Limit: 10,
+//      Count: true,
+//  })
+//  // 加载关联数据，每一层关联关系只执行一次查询
+//  err := e.Preload(&users, "Posts.Comments", "Group")
+//  // 键集分页，按 id 正序，获取 token 对应的记录之后的 10 条数据
+//  cursor, err := sqlbuilder.ParseCursor(token)
+//  stmt := sqlbuilder.Select(e, e.Dialect()).Select("*").From("{#user}").Asc("{id}").Limit(10).After(cursor)
+//  // QueryObj() 同时返回第一条和最后一条记录的游标，
+//  // prev 和 next 可以通过 Encode() 编码之后返回给客户端，用于获取上一页和下一页
+//  _, prev, next, err := stmt.QueryObj(&users)
+//  // 条件辅助函数：id 在 ids 中、age 在 18 到 30 之间且 name 包含 keyword 的数据，
+//  // 切片会被展开成对应数量的占位符，EscapeLike() 用于转义 keyword 中的 % 和 _ 等字符
+//  stmt = sqlbuilder.Select(e, e.Dialect()).Select("*").From("{#user}").
+//      AndIn("{id}", ids).
+//      AndBetween("{age}", 18, 30).
+//      AndLike("{name}", "%"+sqlbuilder.EscapeLike(keyword)+"%").
+//      AndNotNull("{email}")
+//  // 子查询：*SelectStmt 可以作为列、表以及 IN、EXISTS 的条件，参数会按位置自动合并
+//  active := sqlbuilder.Select(e, e.Dialect()).Select("*").From("{#user}").Where("{active}=?", true)
+//  banned := sqlbuilder.Select(e, e.Dialect()).Select("{uid}").From("{#ban}")
+//  cnt := sqlbuilder.Select(e, e.Dialect()).Count("COUNT(*)").From("{#post} AS p").Where("p.uid=u.id")
+//  stmt = sqlbuilder.Select(e, e.Dialect()).Select("u.*").SelectQuery(cnt, "posts").
+//      FromQuery(active, "u").
+//      AndNotIn("u.id", banned)
+//  // 合并多条查询语句的结果，可以使用 Union()、UnionAll()、Intersect() 和 Except()，
+//  // 排序和分页作用于合并之后的结果
+//  admins := sqlbuilder.Select(e, e.Dialect()).Select("{id}", "{name}").From("{#admin}")
+//  guests := sqlbuilder.Select(e, e.Dialect()).Select("{id}", "{name}").From("{#guest}")
+//  _, err = sqlbuilder.Compound(e, e.Dialect(), admins).UnionAll(guests).Asc("{id}").Limit(10).QueryObj(&users)
+//  // 公用表表达式：获取 id 为 1 的分类及其所有子分类，参数会按语句中的位置自动排列
+//  root := sqlbuilder.Select(e, e.Dialect()).Select("{id}").From("{#cat}").Where("{id}=?", 1)
+//  children := sqlbuilder.Select(e, e.Dialect()).Select("c.{id}").From("{#cat} AS c").Join("INNER", "tree AS t", "c.{parent}=t.{id}")
+//  tree := sqlbuilder.Compound(e, e.Dialect(), root).UnionAll(children)
+//  _, _, _, err = sqlbuilder.Select(e, e.Dialect()).WithRecursive("tree", tree, "id").
+//      Select("*").From("{#cat}").Where("{id} IN(SELECT {id} FROM tree)").QueryObj(&cats)
+//  // UpdateStmt 和 DeleteStmt 同样可以通过 With() 和 WithRecursive() 指定
+//  _, err = sqlbuilder.Delete(e).WithRecursive("tree", tree, "id").Table("{#cat}").Where("{id} IN(SELECT {id} FROM tree)").Exec()
+//
+// 钩子:
+// 对象可以实现 BeforeInserter、AfterInserter、BeforeUpdater、AfterUpdater、
+// BeforeDeleter、AfterDeleter 和 AfterFinder 等接口，在对应的操作前后被调用。
+// 接口的参数为当前操作所使用的 DB 或是 Tx，Before 系列的接口返回错误时，将中止该操作。
+//  func (u *User) BeforeInsert(e orm.Engine) error {
+//      u.Created = time.Now().Unix()
+//      return nil
+//  }
 //
 // Query/Exec:
 //  // Query 返回参数与 sql.Query 是相同的
@@ -158,6 +260,21 @@
 //  // Exec 返回参数与 sql.Exec 是相同的
 //  sql = "update #tbl_name set name=? where id=?"
 //  r, err := e.Exec(sql, []interface{}{"name1", 5})
+//  // 以 @name 表示命名参数，其值可以是 sql.NamedArg，
+//  // 或是由唯一的 map[string]interface{} 或结构体参数提供
+//  sql = "update #tbl_name set {name}=@name where {id}=@id"
+//  r, err = e.Exec(sql, map[string]interface{}{"name": "name1", "id": 5})
+//  r, err = e.Exec(sql, &User{ID: 5, Name: "name1"})
+//
+// 通过 DB.AddInterceptor() 可以监视 DB 及其 Tx 执行的每一条 SQL 语句：
+//  db.AddInterceptor(orm.InterceptorFunc(func(ctx context.Context, e *orm.Event) {
+//      log.Println(e.Type, e.Query, e.Duration, e.Err)
+//  }))
+//
+// 通过 DB.SetStmtCache() 可以缓存执行过的 DML 语句的预编译结果，
+// 包括 DB 和 Tx 中的模型操作以及 sqlbuilder 中各语句的执行，
+// 超出指定数量时，最近最少使用的语句会被关闭：
+//  err := db.SetStmtCache(100)
 //
 // 事务：
 //
@@ -165,6 +282,16 @@
 // 返回事务对象 Tx，当然并不是所有的数据库都支持事务操作的。
 // Tx拥有一组与 DB 相同的接口，另外还提供了一组以 `Mult` 开头的函数，
 // 用以同时操作多条记录的。
+//
+// 也可以通过 DB.DoTransaction() 在事务中执行一个函数，
+// 根据函数的返回值自动提交或是回滚事务：
+//  err := db.DoTransaction(ctx, nil, func(tx *orm.Tx) error {
+//      _, err := tx.Insert(&User{FirstName:"abc"})
+//      return err
+//  })
+//
+// Tx.Begin() 会以保存点的形式在当前事务中开始一个嵌套事务，
+// 嵌套事务的 Commit() 和 Rollback() 仅作用于该保存点。
 package orm
 
 // 数据表的更改，涉及到很多方面：
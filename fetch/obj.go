@@ -47,26 +47,67 @@ var ErrInvalidKind = errors.New("无效的 Kind 类型")
 func Obj(obj interface{}, rows *sql.Rows) (int, error) {
 	val := reflect.ValueOf(obj)
 
+	var once bool
+	switch val.Kind() {
+	case reflect.Ptr:
+		switch val.Elem().Kind() {
+		case reflect.Slice, reflect.Array:
+		case reflect.Struct: // 结构指针，只能导出一个
+			once = true
+		default:
+			return 0, ErrInvalidKind
+		}
+	case reflect.Slice:
+	default:
+		return 0, ErrInvalidKind
+	}
+
+	mapped, err := Map(once, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	return ObjFromMap(obj, mapped)
+}
+
+// ObjFromMap 将由 Map() 导出的数据 mapped 写入到 obj 中。
+//
+// obj 的类型及规则与 Obj() 相同。
+// 在需要同时访问原始数据和对象时，可以先用 Map() 导出数据，再调用此函数。
+func ObjFromMap(obj interface{}, mapped []map[string]interface{}) (int, error) {
+	val := reflect.ValueOf(obj)
+
 	switch val.Kind() {
 	case reflect.Ptr:
 		elem := val.Elem()
 		switch elem.Kind() {
 		case reflect.Slice: // slice 指针，可以增长
-			return fetchObjToSlice(val, rows)
+			return fetchObjToSlice(val, mapped)
 		case reflect.Array: // 数组指针，只能按其大小导出
-			return fetchObjToFixedSlice(elem, rows)
+			return fetchObjToFixedSlice(elem, mapped)
 		case reflect.Struct: // 结构指针，只能导出一个
-			return fetchOnceObj(elem, rows)
+			return fetchOnceObj(elem, mapped)
 		default:
 			return 0, ErrInvalidKind
 		}
 	case reflect.Slice: // slice 只能按其大小导出。
-		return fetchObjToFixedSlice(val, rows)
+		return fetchObjToFixedSlice(val, mapped)
 	default:
 		return 0, ErrInvalidKind
 	}
 }
 
+// Fields 获取结构体 v 中与列对应的字段，键名为列名。
+//
+// 列名的规则与 Obj() 相同，v 也可以是结构体指针。
+func Fields(v reflect.Value) (map[string]reflect.Value, error) {
+	ret := make(map[string]reflect.Value, 10)
+	if err := parseObj(v, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
 // 将 v 转换成 map[string]reflect.Value 形式，其中键名为对象的字段名，
 // 键值为字段的值。支持匿名字段，不会转换不可导出(小写字母开头)的
 // 字段，也不会转换 struct tag 以-开头的字段。
@@ -115,20 +156,15 @@ func parseObj(v reflect.Value, ret *map[string]reflect.Value) error {
 	return nil
 }
 
-// 将 rows 中的一条记录写入到 val 中，必须保证 val 的类型为 reflect.Struct。
-// 仅供 Obj() 调用。
-func fetchOnceObj(val reflect.Value, rows *sql.Rows) (int, error) {
-	mapped, err := Map(true, rows)
-	if err != nil {
-		return 0, err
-	}
-
+// 将 mapped 中的第一条记录写入到 val 中，必须保证 val 的类型为 reflect.Struct。
+// 仅供 ObjFromMap() 调用。
+func fetchOnceObj(val reflect.Value, mapped []map[string]interface{}) (int, error) {
 	if len(mapped) == 0 { // 没有导出的数据
 		return 0, nil
 	}
 
 	objItem := make(map[string]reflect.Value, len(mapped[0]))
-	if err = parseObj(val, &objItem); err != nil {
+	if err := parseObj(val, &objItem); err != nil {
 		return 0, err
 	}
 
@@ -137,7 +173,7 @@ func fetchOnceObj(val reflect.Value, rows *sql.Rows) (int, error) {
 		if !found {
 			continue
 		}
-		if err = conv.Value(v, item); err != nil {
+		if err := conv.Value(v, item); err != nil {
 			return 0, err
 		}
 	}
@@ -145,11 +181,11 @@ func fetchOnceObj(val reflect.Value, rows *sql.Rows) (int, error) {
 	return 1, nil
 }
 
-// 将 rows 中的记录按 obj 的长度数量导出到 obj 中。
+// 将 mapped 中的记录按 obj 的长度数量导出到 obj 中。
 // val 的类型必须是 reflect.Slice 或是 reflect.Array.
 // 可能只有部分数据被成功导入，而后发生 error，
 // 此时只能通过第一个返回参数来判断有多少数据是成功导入的。
-func fetchObjToFixedSlice(val reflect.Value, rows *sql.Rows) (int, error) {
+func fetchObjToFixedSlice(val reflect.Value, mapped []map[string]interface{}) (int, error) {
 	itemType := val.Type().Elem()
 	for itemType.Kind() == reflect.Ptr {
 		itemType = itemType.Elem()
@@ -158,12 +194,6 @@ func fetchObjToFixedSlice(val reflect.Value, rows *sql.Rows) (int, error) {
 		return 0, ErrInvalidKind
 	}
 
-	// 先导出数据到 map 中
-	mapped, err := Map(false, rows)
-	if err != nil {
-		return 0, err
-	}
-
 	l := len(mapped)
 	if l > val.Len() {
 		l = val.Len()
@@ -171,7 +201,7 @@ func fetchObjToFixedSlice(val reflect.Value, rows *sql.Rows) (int, error) {
 
 	for i := 0; i < l; i++ {
 		objItem := make(map[string]reflect.Value, len(mapped[i]))
-		if err = parseObj(val.Index(i), &objItem); err != nil {
+		if err := parseObj(val.Index(i), &objItem); err != nil {
 			return 0, err
 		}
 		for index, item := range objItem {
@@ -179,7 +209,7 @@ func fetchObjToFixedSlice(val reflect.Value, rows *sql.Rows) (int, error) {
 			if !found {
 				continue
 			}
-			if err = conv.Value(v, item); err != nil {
+			if err := conv.Value(v, item); err != nil {
 				return i, err // 已经有 i 条数据被正确导出
 			}
 		} // end for objItem
@@ -188,11 +218,11 @@ func fetchObjToFixedSlice(val reflect.Value, rows *sql.Rows) (int, error) {
 	return l, nil
 }
 
-// 将 rows 中的所有记录导出到 val 中，val 必须为 slice 的指针。
-// 若 val 的长度不够，会根据 rowsa 中的长度调整。
+// 将 mapped 中的所有记录导出到 val 中，val 必须为 slice 的指针。
+// 若 val 的长度不够，会根据 mapped 中的长度调整。
 // 可能只有部分数据被成功导入，而后发生 error，
 // 此时只能通过第一个返回参数来判断有多少数据是成功导入的。
-func fetchObjToSlice(val reflect.Value, rows *sql.Rows) (int, error) {
+func fetchObjToSlice(val reflect.Value, mapped []map[string]interface{}) (int, error) {
 	elem := val.Elem()
 
 	itemType := elem.Type().Elem()
@@ -203,12 +233,6 @@ func fetchObjToSlice(val reflect.Value, rows *sql.Rows) (int, error) {
 		return 0, ErrInvalidKind
 	}
 
-	// 先导出数据到 map 中
-	mapped, err := Map(false, rows)
-	if err != nil {
-		return 0, err
-	}
-
 	// 使 elem 表示的数组长度最起码和 mapped 一样。
 	size := len(mapped) - elem.Len()
 	if size > 0 {
@@ -220,7 +244,7 @@ func fetchObjToSlice(val reflect.Value, rows *sql.Rows) (int, error) {
 
 	for i := 0; i < len(mapped); i++ {
 		objItem := make(map[string]reflect.Value, len(mapped[i]))
-		if err = parseObj(elem.Index(i), &objItem); err != nil {
+		if err := parseObj(elem.Index(i), &objItem); err != nil {
 			return 0, err
 		}
 
@@ -229,7 +253,7 @@ func fetchObjToSlice(val reflect.Value, rows *sql.Rows) (int, error) {
 			if !found {
 				continue
 			}
-			if err = conv.Value(e, item); err != nil {
+			if err := conv.Value(e, item); err != nil {
 				return i, err
 			}
 		} // end for objItem
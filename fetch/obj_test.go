@@ -59,6 +59,14 @@ func TestParseObj(t *testing.T) {
 	a.Equal("username", obj.Username)
 	mapped["group"].SetInt(1)
 	a.Equal(1, obj.Group)
+
+	// Fields
+	fields, err := Fields(reflect.ValueOf(obj))
+	a.NotError(err).Equal(len(fields), 4)
+	a.Equal(fields["id"].Interface(), 36)
+
+	fields, err = Fields(reflect.ValueOf(5))
+	a.Equal(err, ErrInvalidKind).Nil(fields)
 }
 
 // 初始化一个sql.DB(sqlite3)，方便后面的测试用例使用。
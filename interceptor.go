@@ -0,0 +1,98 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// 事件的类型
+const (
+	EventQuery EventType = iota + 1
+	EventExec
+	EventPrepare
+)
+
+// EventType 表示 Event 的类型
+type EventType int8
+
+// Event 表示一次 SQL 语句的执行信息
+type Event struct {
+	Type EventType
+
+	// 最终提交给数据库的 SQL 语句，即已经经过占位符替换和 Dialect.SQL() 转换之后的内容。
+	Query string
+
+	// 执行时传递的参数，Prepare 时为空。
+	Args []interface{}
+
+	// 是否在事务中执行
+	Tx bool
+
+	// 执行所花费的时间
+	Duration time.Duration
+
+	// 受影响的行数，仅在 Exec 成功时有效，其它情况下为 -1。
+	RowsAffected int64
+
+	// 执行时返回的错误信息
+	Err error
+}
+
+// Interceptor 用于监视 DB 及其关联的 Tx 执行的每一条 SQL 语句，
+// 包括由 sqlbuilder 和 Model 相关的操作所产生的语句。
+//
+// 可用于记录日志、统计慢查询或是生成跟踪信息等。
+// Intercept 在语句执行完成之后被调用，不能修改执行的结果。
+type Interceptor interface {
+	Intercept(ctx context.Context, e *Event)
+}
+
+// InterceptorFunc 将一个函数转换成 Interceptor 接口
+type InterceptorFunc func(ctx context.Context, e *Event)
+
+// Intercept 实现 Interceptor 接口
+func (f InterceptorFunc) Intercept(ctx context.Context, e *Event) {
+	f(ctx, e)
+}
+
+// AddInterceptor 添加一个 Interceptor 实例
+//
+// 非协程安全，应该在初始化 DB 之后，执行其它语句之前调用。
+func (db *DB) AddInterceptor(i Interceptor) {
+	db.interceptors = append(db.interceptors, i)
+}
+
+func (db *DB) intercept(ctx context.Context, e stdEngine, typ EventType, query string, args []interface{}, start time.Time, affected int64, err error) {
+	_, tx := e.(*sql.Tx)
+	event := &Event{
+		Type:         typ,
+		Query:        query,
+		Args:         args,
+		Tx:           tx,
+		Duration:     time.Since(start),
+		RowsAffected: affected,
+		Err:          err,
+	}
+
+	for _, i := range db.interceptors {
+		i.Intercept(ctx, event)
+	}
+}
+
+func (t EventType) String() string {
+	switch t {
+	case EventQuery:
+		return "QUERY"
+	case EventExec:
+		return "EXEC"
+	case EventPrepare:
+		return "PREPARE"
+	default:
+		return "<unknown>"
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/issue9/assert"
+	"github.com/issue9/orm"
+	"github.com/issue9/orm/internal/modeltest"
+)
+
+var _ orm.Interceptor = orm.InterceptorFunc(nil)
+
+func TestDB_AddInterceptor(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer clearData(db, a)
+	a.NotError(db.Create(&modeltest.User{}))
+
+	events := make([]*orm.Event, 0, 10)
+	db.AddInterceptor(orm.InterceptorFunc(func(ctx context.Context, e *orm.Event) {
+		events = append(events, e)
+	}))
+
+	// Exec
+	_, err := db.Insert(&modeltest.User{Username: "u1"})
+	a.NotError(err)
+	a.Equal(1, len(events))
+	e := events[0]
+	a.Equal(e.Type, orm.EventExec).
+		Equal(e.RowsAffected, 1).
+		Equal(len(e.Args), 2).
+		False(e.Tx).
+		NotError(e.Err)
+	a.True(strings.Contains(e.Query, prefix+"users"), e.Query) // 已经替换表名前缀
+
+	// Query
+	a.NotError(db.Select(&modeltest.User{ID: 1}))
+	a.Equal(2, len(events))
+	e = events[1]
+	a.Equal(e.Type, orm.EventQuery).Equal(e.RowsAffected, -1).Equal(e.Args, []interface{}{1})
+
+	// 错误的语句
+	_, err = db.Exec("SELECT * FROM #not_exists")
+	a.Error(err)
+	a.Equal(3, len(events))
+	a.Error(events[2].Err)
+
+	// Tx
+	tx, err := db.Begin()
+	a.NotError(err)
+	stmt, err := tx.Prepare("SELECT * FROM #users WHERE id=?")
+	a.NotError(err).NotNil(stmt)
+	a.NotError(stmt.Close())
+	a.NotError(tx.Commit())
+	a.Equal(4, len(events))
+	e = events[3]
+	a.Equal(e.Type, orm.EventPrepare).True(e.Tx).Empty(e.Args)
+}
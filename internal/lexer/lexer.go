@@ -0,0 +1,154 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+// Package lexer 对 SQL 语句进行简单的分词，
+// 用于区分语句中的字符串、引号包含的标识符以及注释等内容。
+//
+// 仅作为替换语句中的占位符等内容时使用，并不会验证语句的正确性。
+package lexer
+
+import "strings"
+
+// Token 的类型
+const (
+	Code    Kind = iota // 普通的语句内容
+	Literal             // 引号包含的字符串或是标识符
+	Comment             // 注释
+)
+
+// Kind 表示 Token 的类型
+type Kind int8
+
+// Token 表示语句中的一段内容
+type Token struct {
+	Kind Kind
+	Text string
+}
+
+// Split 将 sql 拆分成 Token 数组，所有 Token.Text 依次拼接即为原来的 sql。
+//
+// 可以识别以下内容：
+//  '...'、"..."、`...` 以及 postgres 的 $tag$...$tag$ 和 E'...'；
+//  -- 开头的单行注释以及 /* ... */ 多行注释。
+// backslash 表示引号中的反斜杠是否作为转义字符，比如 mysql 的 'it\'s'。
+// 未闭合的字符串或是注释，会一直延续到语句的结尾。
+func Split(sql string, backslash bool) []Token {
+	tokens := make([]Token, 0, 10)
+
+	start := 0 // 当前 Code 的起始位置
+	push := func(kind Kind, begin, end int) {
+		if begin > start {
+			tokens = append(tokens, Token{Kind: Code, Text: sql[start:begin]})
+		}
+		tokens = append(tokens, Token{Kind: kind, Text: sql[begin:end]})
+		start = end
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			escape := backslash && c != '`'
+			if c == '\'' && i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') && (i == 1 || !IsWord(sql[i-2])) {
+				escape = true
+			}
+			end := quoteEnd(sql, i, escape)
+			push(Literal, i, end)
+			i = end
+		case c == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := indexFrom(sql, i+2, "\n")
+			push(Comment, i, end)
+			i = end
+		case c == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := indexFrom(sql, i+2, "*/")
+			if end < len(sql) {
+				end += 2
+			}
+			push(Comment, i, end)
+			i = end
+		case c == '$' && (i == 0 || !IsWord(sql[i-1])):
+			tag := dollarTag(sql, i)
+			if tag == "" {
+				i++
+				continue
+			}
+			end := indexFrom(sql, i+len(tag), tag)
+			if end < len(sql) {
+				end += len(tag)
+			}
+			push(Literal, i, end)
+			i = end
+		default:
+			i++
+		}
+	}
+
+	if start < len(sql) {
+		tokens = append(tokens, Token{Kind: Code, Text: sql[start:]})
+	}
+
+	return tokens
+}
+
+// 查找以 sql[start] 开始的引号的结束位置，返回值为结束引号之后的位置。
+//
+// 连续两个引号表示引号本身；escape 为 true 时，反斜杠之后的字符也不作为结束引号。
+func quoteEnd(sql string, start int, escape bool) int {
+	quote := sql[start]
+
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			if escape {
+				i++
+			}
+		case quote:
+			if i+1 < len(sql) && sql[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+
+	return len(sql)
+}
+
+// 获取以 sql[start] 开始的 $tag$，若不是合法的 $tag$ 格式，返回空值。
+//
+// $1 等 postgres 的占位符不会被当作 $tag$。
+func dollarTag(sql string, start int) string {
+	for i := start + 1; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '$':
+			return sql[start : i+1]
+		case c >= '0' && c <= '9':
+			if i == start+1 {
+				return ""
+			}
+		case !IsWord(c):
+			return ""
+		}
+	}
+
+	return ""
+}
+
+// 从 sql[start:] 中查找 sep 的位置，找不到则返回 sql 的长度。
+func indexFrom(sql string, start int, sep string) int {
+	if index := strings.Index(sql[start:], sep); index >= 0 {
+		return start + index
+	}
+	return len(sql)
+}
+
+// IsWord 判断 c 是否可以作为标识符的组成部分
+func IsWord(c byte) bool {
+	return c == '_' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') ||
+		c >= 0x80
+}
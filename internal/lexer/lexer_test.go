@@ -0,0 +1,70 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+
+	"github.com/issue9/assert"
+)
+
+func TestSplit(t *testing.T) {
+	a := assert.New(t)
+
+	tokens := Split("SELECT * FROM #t WHERE a='a#b' AND {b}=\"{c}\" -- #x\nAND `#d`=? /* {e} */", false)
+	a.Equal(tokens, []Token{
+		{Kind: Code, Text: "SELECT * FROM #t WHERE a="},
+		{Kind: Literal, Text: "'a#b'"},
+		{Kind: Code, Text: " AND {b}="},
+		{Kind: Literal, Text: "\"{c}\""},
+		{Kind: Code, Text: " "},
+		{Kind: Comment, Text: "-- #x"},
+		{Kind: Code, Text: "\nAND "},
+		{Kind: Literal, Text: "`#d`"},
+		{Kind: Code, Text: "=? "},
+		{Kind: Comment, Text: "/* {e} */"},
+	})
+
+	// 连续两个引号
+	tokens = Split("a='it''s' AND #b", false)
+	a.Equal(tokens, []Token{
+		{Kind: Code, Text: "a="},
+		{Kind: Literal, Text: "'it''s'"},
+		{Kind: Code, Text: " AND #b"},
+	})
+
+	// 反斜杠
+	tokens = Split(`a='c:\' AND #b='\''`, false)
+	a.Equal(tokens[1], Token{Kind: Literal, Text: `'c:\'`})
+	tokens = Split(`a='it\'s' AND #b`, true)
+	a.Equal(tokens, []Token{
+		{Kind: Code, Text: "a="},
+		{Kind: Literal, Text: `'it\'s'`},
+		{Kind: Code, Text: " AND #b"},
+	})
+	tokens = Split(`a=E'it\'s' AND #b`, false)
+	a.Equal(tokens[1], Token{Kind: Literal, Text: `'it\'s'`})
+
+	// $tag$
+	tokens = Split("a=$$it's$$ AND b=$fn$ {x} $fn$ AND c=$1 AND d$e=?", false)
+	a.Equal(tokens, []Token{
+		{Kind: Code, Text: "a="},
+		{Kind: Literal, Text: "$$it's$$"},
+		{Kind: Code, Text: " AND b="},
+		{Kind: Literal, Text: "$fn$ {x} $fn$"},
+		{Kind: Code, Text: " AND c=$1 AND d$e=?"},
+	})
+
+	// 未闭合
+	tokens = Split("a='#b", false)
+	a.Equal(tokens, []Token{
+		{Kind: Code, Text: "a="},
+		{Kind: Literal, Text: "'#b"},
+	})
+	tokens = Split("a /* #b", false)
+	a.Equal(tokens[1], Token{Kind: Comment, Text: "/* #b"})
+
+	a.Empty(Split("", false))
+}
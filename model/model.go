@@ -11,12 +11,15 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
 	"github.com/issue9/orm/fetch"
 	"github.com/issue9/orm/internal/tags"
 )
 
+var timeType = reflect.TypeOf(time.Time{})
+
 // model 缓存
 var models = &modelsMap{items: map[reflect.Type]*Model{}}
 
@@ -29,12 +32,17 @@ type modelsMap struct {
 type Model struct {
 	Name          string                 // 表的名称
 	Cols          map[string]*Column     // 所有的列
+	Columns       []*Column              // 所有的列，按字段的定义顺序排列
 	KeyIndexes    map[string][]*Column   // 索引列
 	UniqueIndexes map[string][]*Column   // 唯一索引列
 	FK            map[string]*ForeignKey // 外键
 	PK            []*Column              // 主键
 	AI            *Column                // 自增列
 	OCC           *Column                // 乐观锁
+	SoftDelete    *Column                // 软删除
+	Created       *Column                // 创建时间
+	Updated       *Column                // 更新时间
+	Relations     map[string]*Relation   // 关联关系，键名为字段名
 	Check         map[string]string      // Check 键名为约束名，键值为约束表达式
 	Meta          map[string][]string    // 表级别的数据，如存储引擎，表名和字符集等。
 
@@ -71,6 +79,7 @@ func New(obj interface{}) (*Model, error) {
 		UniqueIndexes: map[string][]*Column{},
 		Name:          rtype.Name(),
 		FK:            map[string]*ForeignKey{},
+		Relations:     map[string]*Relation{},
 		Check:         map[string]string{},
 		Meta:          map[string][]string{},
 		constraints:   map[string]conType{},
@@ -123,10 +132,15 @@ func (m *Model) parseColumn(field reflect.StructField) (err error) {
 
 	if len(tagTxt) == 0 { // 没有附加的 struct tag，直接取得几个关键信息返回。
 		m.Cols[col.Name] = col
+		m.Columns = append(m.Columns, col)
 		return nil
 	}
 
 	tags := tags.Parse(tagTxt)
+	if found, err := m.parseRelation(field, tags); found { // 关联关系不作为列
+		return err
+	}
+
 	for k, v := range tags {
 		switch k {
 		case "name": // name(colname)
@@ -152,6 +166,12 @@ func (m *Model) parseColumn(field reflect.StructField) (err error) {
 			err = m.setDefault(col, v)
 		case "occ":
 			err = m.setOCC(col, v)
+		case "softdelete":
+			err = m.setSoftDelete(col, v)
+		case "created":
+			err = m.setTimestamp(col, "created", &m.Created, v)
+		case "updated":
+			err = m.setTimestamp(col, "updated", &m.Updated, v)
 		default:
 			err = propertyError(col.Name, k, "未知的属性")
 		}
@@ -160,8 +180,15 @@ func (m *Model) parseColumn(field reflect.StructField) (err error) {
 			return err
 		}
 	}
+
+	// tags 为 map，无法保证 nullable 与 softdelete 的先后顺序，所以在最后检测。
+	if m.SoftDelete == col && !col.Nullable {
+		return propertyError(col.Name, "softdelete", "软删除列必须同时指定 nullable")
+	}
+
 	// col.Name 可能在上面的 for 循环中被更改，所以要在最后再添加到 m.Cols 中
 	m.Cols[col.Name] = col
+	m.Columns = append(m.Columns, col)
 
 	return nil
 }
@@ -244,6 +271,49 @@ func (m *Model) setOCC(c *Column, vals []string) error {
 	return nil
 }
 
+// softdelete
+func (m *Model) setSoftDelete(c *Column, vals []string) error {
+	if len(vals) != 0 {
+		return propertyError(c.Name, "softdelete", "太多的值")
+	}
+
+	if m.SoftDelete != nil {
+		return propertyError(c.Name, "softdelete", "已经指定了一个软删除列")
+	}
+
+	if c.GoType != timeType {
+		return propertyError(c.Name, "softdelete", "类型只能是 time.Time")
+	}
+
+	m.SoftDelete = c
+	return nil
+}
+
+// created 或是 updated
+//
+// 列的类型可以是 time.Time 或是表示 unix 时间戳的整数。
+func (m *Model) setTimestamp(c *Column, name string, dest **Column, vals []string) error {
+	if len(vals) != 0 {
+		return propertyError(c.Name, name, "太多的值")
+	}
+
+	if *dest != nil {
+		return propertyError(c.Name, name, "已经指定了一个相同类型的列")
+	}
+
+	switch c.GoType.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		if c.GoType != timeType {
+			return propertyError(c.Name, name, "类型只能是 time.Time 或是整数")
+		}
+	}
+
+	*dest = c
+	return nil
+}
+
 // default(5)
 func (m *Model) setDefault(col *Column, vals []string) error {
 	if m.AI == col {
@@ -5,7 +5,9 @@
 package model
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/issue9/assert"
 	"github.com/issue9/orm/internal/modeltest"
@@ -60,6 +62,10 @@ func TestModel(t *testing.T) {
 	groupCol, found := m.Cols["group"]
 	a.True(found)
 
+	// 按字段的定义顺序排列
+	a.Equal(len(m.Columns), len(m.Cols))
+	a.Equal(m.Columns[0], idCol).Equal(m.Columns[1], usernameCol).Equal(m.Columns[len(m.Columns)-1], groupCol)
+
 	// index
 	index, found := m.KeyIndexes["index_name"]
 	a.True(found).Equal(usernameCol, index[0])
@@ -95,3 +101,118 @@ func TestModel(t *testing.T) {
 	// Meta返回的name属性
 	a.Equal(m.Name, "administrators")
 }
+
+func TestModel_softDelete(t *testing.T) {
+	a := assert.New(t)
+
+	m, err := New(&struct {
+		ID      int       `orm:"name(id);ai"`
+		Deleted time.Time `orm:"name(deleted);softdelete;nullable"`
+	}{})
+	a.NotError(err).NotNil(m)
+	a.Equal(m.SoftDelete, m.Cols["deleted"])
+
+	// 未指定 nullable
+	m, err = New(&struct {
+		Deleted time.Time `orm:"name(deleted);softdelete"`
+	}{})
+	a.Error(err).Nil(m)
+
+	// 类型不正确
+	m, err = New(&struct {
+		Deleted int64 `orm:"name(deleted);softdelete;nullable"`
+	}{})
+	a.Error(err).Nil(m)
+
+	// 多个软删除列
+	m, err = New(&struct {
+		Deleted1 time.Time `orm:"softdelete;nullable"`
+		Deleted2 time.Time `orm:"softdelete;nullable"`
+	}{})
+	a.Error(err).Nil(m)
+}
+
+func TestModel_timestamp(t *testing.T) {
+	a := assert.New(t)
+
+	m, err := New(&struct {
+		ID      int       `orm:"name(id);ai"`
+		Created time.Time `orm:"name(created);created"`
+		Updated int64     `orm:"name(updated);updated"`
+	}{})
+	a.NotError(err).NotNil(m)
+	a.Equal(m.Created, m.Cols["created"]).
+		Equal(m.Updated, m.Cols["updated"])
+
+	// 类型不正确
+	m, err = New(&struct {
+		Created string `orm:"name(created);created"`
+	}{})
+	a.Error(err).Nil(m)
+
+	// 多个 updated 列
+	m, err = New(&struct {
+		Updated1 time.Time `orm:"updated"`
+		Updated2 time.Time `orm:"updated"`
+	}{})
+	a.Error(err).Nil(m)
+}
+
+func TestModel_relations(t *testing.T) {
+	a := assert.New(t)
+
+	type tag struct {
+		ID int64 `orm:"name(id);ai"`
+	}
+
+	type comment struct {
+		ID int64 `orm:"name(id);ai"`
+	}
+
+	type post struct {
+		ID       int64           `orm:"name(id);ai"`
+		UID      int64           `orm:"name(uid);fk(fk_post_user,#users,id)"`
+		User     *modeltest.User `orm:"belongsto(fk_post_user)"`
+		Comments []*comment      `orm:"hasmany(fk_comment_post)"`
+		Tags     []tag           `orm:"manytomany(#post_tags,post_id,tag_id)"`
+	}
+
+	m, err := New(&post{})
+	a.NotError(err).NotNil(m)
+	a.Equal(len(m.Cols), 2).Equal(len(m.Relations), 3)
+
+	rel := m.Relations["User"]
+	a.Equal(rel.Type, BelongsTo).
+		Equal(rel.FK, "fk_post_user").
+		Equal(rel.GoType, reflect.TypeOf(modeltest.User{}))
+
+	rel = m.Relations["Comments"]
+	a.Equal(rel.Type, HasMany).
+		Equal(rel.FK, "fk_comment_post").
+		Equal(rel.GoType, reflect.TypeOf(comment{}))
+
+	rel = m.Relations["Tags"]
+	a.Equal(rel.Type, ManyToMany).
+		Equal(rel.JoinTable, "post_tags").
+		Equal(rel.JoinCol, "post_id").
+		Equal(rel.JoinRefCol, "tag_id").
+		Equal(rel.GoType, reflect.TypeOf(tag{}))
+
+	// hasmany 的类型只能是切片
+	m, err = New(&struct {
+		Comments *comment `orm:"hasmany(fk_comment_post)"`
+	}{})
+	a.Error(err).Nil(m)
+
+	// 不能与其它属性同时使用
+	m, err = New(&struct {
+		User *modeltest.User `orm:"name(user);belongsto(fk_post_user)"`
+	}{})
+	a.Error(err).Nil(m)
+
+	// 参数个数不正确
+	m, err = New(&struct {
+		Tags []tag `orm:"manytomany(post_tags,post_id)"`
+	}{})
+	a.Error(err).Nil(m)
+}
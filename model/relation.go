@@ -0,0 +1,121 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package model
+
+import (
+	"reflect"
+	"strings"
+)
+
+// 关联关系的类型
+const (
+	BelongsTo RelationType = iota + 1
+	HasOne
+	HasMany
+	ManyToMany
+)
+
+// RelationType 表示关联关系的类型
+type RelationType int8
+
+// Relation 表示当前模型与其它模型之间的关联关系。
+//
+// 关联关系仅用于读取关联的数据，不会参与表的创建等操作。
+type Relation struct {
+	Type   RelationType
+	GoName string       // 结构体中的字段名
+	GoType reflect.Type // 关联模型的类型，去掉了指针和切片等
+
+	// 外键约束名。
+	//
+	// BelongsTo 为当前模型中定义的外键；
+	// HasOne 和 HasMany 为关联模型中定义的外键，引用当前模型中的列。
+	FK string
+
+	// ManyToMany 的中间表，JoinCol 为引用当前模型主键的列，
+	// JoinRefCol 为引用关联模型主键的列。
+	JoinTable, JoinCol, JoinRefCol string
+}
+
+var relationTypes = map[string]RelationType{
+	"belongsto":  BelongsTo,
+	"hasone":     HasOne,
+	"hasmany":    HasMany,
+	"manytomany": ManyToMany,
+}
+
+// 分析 tags 中的关联关系，若 tags 不包含关联关系，返回的 found 为 false。
+//
+// belongsto(fk_name)
+// hasone(fk_name)
+// hasmany(fk_name)
+// manytomany(join_table,join_col,join_ref_col)
+func (m *Model) parseRelation(field reflect.StructField, tags map[string][]string) (found bool, err error) {
+	for name, vals := range tags {
+		typ, ok := relationTypes[name]
+		if !ok {
+			continue
+		}
+
+		if len(tags) != 1 {
+			return true, propertyError(field.Name, name, "不能与其它属性同时使用")
+		}
+
+		return true, m.setRelation(field, name, typ, vals)
+	}
+
+	return false, nil
+}
+
+func (m *Model) setRelation(field reflect.StructField, name string, typ RelationType, vals []string) error {
+	rel := &Relation{Type: typ, GoName: field.Name}
+
+	if typ == ManyToMany {
+		if len(vals) != 3 {
+			return propertyError(field.Name, name, "参数个数不正确")
+		}
+		rel.JoinTable = strings.TrimPrefix(vals[0], "#")
+		rel.JoinCol = vals[1]
+		rel.JoinRefCol = vals[2]
+	} else {
+		if len(vals) != 1 {
+			return propertyError(field.Name, name, "参数个数不正确")
+		}
+		rel.FK = vals[0]
+	}
+
+	t := field.Type
+	if typ == HasMany || typ == ManyToMany {
+		if t.Kind() != reflect.Slice {
+			return propertyError(field.Name, name, "类型只能是切片")
+		}
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return propertyError(field.Name, name, "关联的类型只能是结构体")
+	}
+	rel.GoType = t
+
+	m.Relations[field.Name] = rel
+	return nil
+}
+
+func (t RelationType) String() string {
+	switch t {
+	case BelongsTo:
+		return "BELONGS TO"
+	case HasOne:
+		return "HAS ONE"
+	case HasMany:
+		return "HAS MANY"
+	case ManyToMany:
+		return "MANY TO MANY"
+	default:
+		return "<unknown>"
+	}
+}
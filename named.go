@@ -0,0 +1,80 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"time"
+
+	"github.com/issue9/orm/model"
+)
+
+// 若 args 仅包含一个 map[string]interface{} 或是结构体(指针)，
+// 则将其展开成 sql.NamedArg 数组，作为语句中 @name 的值。
+//
+// 结构体中的参数名与模型中的列名相同。仅实现了 model.Metaer 接口，
+// 或是包含 orm 标签的结构体才会被当作模型展开，sql.NamedArg、
+// 实现了 driver.Valuer 的结构体以及 time.Time 等其它结构体会被当作普通的参数值。
+func namedArgs(args []interface{}) ([]interface{}, error) {
+	if len(args) != 1 {
+		return args, nil
+	}
+
+	if m, ok := args[0].(map[string]interface{}); ok {
+		ret := make([]interface{}, 0, len(m))
+		for name, v := range m {
+			ret = append(ret, sql.Named(name, v))
+		}
+		return ret, nil
+	}
+
+	switch args[0].(type) {
+	case driver.Valuer, time.Time, *time.Time, sql.NamedArg, *sql.NamedArg:
+		return args, nil
+	}
+
+	rval := reflect.ValueOf(args[0])
+	for rval.Kind() == reflect.Ptr && !rval.IsNil() {
+		rval = rval.Elem()
+	}
+	if rval.Kind() != reflect.Struct || !isModel(rval.Type()) {
+		return args, nil
+	}
+
+	m, err := model.New(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]interface{}, 0, len(m.Columns))
+	for _, col := range m.Columns {
+		ret = append(ret, sql.Named(col.Name, rval.FieldByName(col.GoName).Interface()))
+	}
+	return ret, nil
+}
+
+var metaerType = reflect.TypeOf((*model.Metaer)(nil)).Elem()
+
+// 判断 rtype 是否为模型，即实现了 model.Metaer 接口或是包含 orm 标签的导出字段。
+func isModel(rtype reflect.Type) bool {
+	if rtype.Implements(metaerType) || reflect.PtrTo(rtype).Implements(metaerType) {
+		return true
+	}
+
+	for i := 0; i < rtype.NumField(); i++ {
+		field := rtype.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && isModel(field.Type) {
+			return true
+		}
+
+		if _, found := field.Tag.Lookup("orm"); found && field.PkgPath == "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,280 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/issue9/orm/fetch"
+	"github.com/issue9/orm/model"
+	"github.com/issue9/orm/sqlbuilder"
+)
+
+// 多对多关系中，保存中间表关联列的值的别名
+const joinKeyName = "orm_join_key"
+
+// 为 objs 加载 paths 指定的关联数据。
+//
+// objs 可以是结构体指针，或是由结构体(指针)组成的切片(指针)。
+func preload(ctx context.Context, e Engine, objs interface{}, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	items, err := preloadItems(reflect.ValueOf(objs))
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	m, err := model.New(items[0].Addr().Interface())
+	if err != nil {
+		return err
+	}
+
+	return preloadPaths(ctx, e, m, items, paths)
+}
+
+// 获取 rval 中所有可写的结构体
+func preloadItems(rval reflect.Value) ([]reflect.Value, error) {
+	for rval.Kind() == reflect.Ptr {
+		if rval.IsNil() {
+			return nil, nil
+		}
+		rval = rval.Elem()
+	}
+
+	switch rval.Kind() {
+	case reflect.Struct:
+		if !rval.CanSet() {
+			return nil, errors.New("预加载的对象必须为指针")
+		}
+		return []reflect.Value{rval}, nil
+	case reflect.Slice, reflect.Array:
+		items := make([]reflect.Value, 0, rval.Len())
+		for i := 0; i < rval.Len(); i++ {
+			item, err := preloadItems(rval.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item...)
+		}
+		return items, nil
+	default:
+		return nil, fetch.ErrInvalidKind
+	}
+}
+
+// 按 paths 依次加载关联数据，paths 中以点号分隔嵌套的关联关系，比如 Posts.Comments。
+func preloadPaths(ctx context.Context, e Engine, m *model.Model, items []reflect.Value, paths []string) error {
+	names := make([]string, 0, len(paths))
+	children := make(map[string][]string, len(paths))
+	for _, path := range paths {
+		name, sub := path, ""
+		if index := strings.IndexByte(path, '.'); index >= 0 {
+			name, sub = path[:index], path[index+1:]
+		}
+
+		if _, found := children[name]; !found {
+			names = append(names, name)
+			children[name] = nil
+		}
+		if sub != "" {
+			children[name] = append(children[name], sub)
+		}
+	}
+
+	for _, name := range names {
+		rel, found := m.Relations[name]
+		if !found {
+			return fmt.Errorf("%s 中不存在关联关系 %s", m.Name, name)
+		}
+
+		if err := loadRelation(ctx, e, m, rel, items, children[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// 为 items 加载 rel 表示的关联数据，paths 为关联数据需要继续加载的关联关系。
+func loadRelation(ctx context.Context, e Engine, m *model.Model, rel *model.Relation, items []reflect.Value, paths []string) error {
+	rm, err := model.New(reflect.New(rel.GoType).Interface())
+	if err != nil {
+		return err
+	}
+
+	// 关联数据的查询条件：本模型中的列 col 的值，对应关联数据中的列 refCol。
+	var col *model.Column
+	var refCol string
+	switch rel.Type {
+	case model.BelongsTo:
+		fk, found := m.FK[rel.FK]
+		if !found {
+			return fmt.Errorf("%s 中不存在外键 %s", m.Name, rel.FK)
+		}
+		if !refersTo(fk, rm) {
+			return fmt.Errorf("%s 的外键 %s 并未引用 %s", m.Name, rel.FK, rm.Name)
+		}
+		if _, found = rm.Cols[fk.RefColName]; !found {
+			return fmt.Errorf("%s 中不存在列 %s", rm.Name, fk.RefColName)
+		}
+		col, refCol = fk.Col, "{"+fk.RefColName+"}"
+	case model.HasOne, model.HasMany:
+		fk, found := rm.FK[rel.FK]
+		if !found {
+			return fmt.Errorf("%s 中不存在外键 %s", rm.Name, rel.FK)
+		}
+		if !refersTo(fk, m) {
+			return fmt.Errorf("%s 的外键 %s 并未引用 %s", rm.Name, rel.FK, m.Name)
+		}
+		if col, found = m.Cols[fk.RefColName]; !found {
+			return fmt.Errorf("%s 中不存在列 %s", m.Name, fk.RefColName)
+		}
+		refCol = "{" + fk.Col.Name + "}"
+	case model.ManyToMany:
+		if len(m.PK) != 1 || len(rm.PK) != 1 {
+			return errors.New("多对多关系的两个模型都必须有且仅有一个主键")
+		}
+		col, refCol = m.PK[0], "j.{"+rel.JoinCol+"}"
+	}
+
+	keys := make([]interface{}, 0, len(items))
+	exists := make(map[string]bool, len(items))
+	for _, item := range items {
+		v := item.FieldByName(col.GoName).Interface()
+		if k := relationKey(v); v != col.Zero && !exists[k] {
+			exists[k] = true
+			keys = append(keys, v)
+		}
+	}
+
+	related, rows, err := fetchRelated(ctx, e, rel, rm, refCol, keys)
+	if err != nil {
+		return err
+	}
+
+	if len(paths) > 0 && len(related) > 0 { // 先加载下一级的数据，再赋值给 items
+		if err = preloadPaths(ctx, e, rm, related, paths); err != nil {
+			return err
+		}
+	}
+
+	// 按关联列的值对关联数据进行分组
+	groups := make(map[string][]reflect.Value, len(keys))
+	for i, r := range related {
+		var k string
+		switch rel.Type {
+		case model.BelongsTo:
+			k = relationKey(r.FieldByName(rm.Cols[strings.Trim(refCol, "{}")].GoName).Interface())
+		case model.HasOne, model.HasMany:
+			k = relationKey(r.FieldByName(rm.FK[rel.FK].Col.GoName).Interface())
+		case model.ManyToMany:
+			k = relationKey(rows[i][joinKeyName])
+		}
+		groups[k] = append(groups[k], r)
+	}
+
+	for _, item := range items {
+		field := item.FieldByName(rel.GoName)
+		group := groups[relationKey(item.FieldByName(col.GoName).Interface())]
+
+		if rel.Type == model.HasMany || rel.Type == model.ManyToMany {
+			slice := reflect.MakeSlice(field.Type(), 0, len(group))
+			for _, r := range group {
+				slice = reflect.Append(slice, relationValue(field.Type().Elem(), r))
+			}
+			field.Set(slice)
+		} else if len(group) > 0 {
+			field.Set(relationValue(field.Type(), group[0]))
+		}
+	}
+
+	return nil
+}
+
+// 外键 fk 引用的表是否为 m，表名中的 # 和 {} 会被忽略。
+func refersTo(fk *model.ForeignKey, m *model.Model) bool {
+	return strings.Trim(fk.RefTableName, "{}#") == m.Name
+}
+
+// 分批查询 rm 中 refCol 的值在 keys 中的数据，
+// 返回数据对应的对象，以及每条数据的原始内容。
+func fetchRelated(ctx context.Context, e Engine, rel *model.Relation, rm *model.Model, refCol string, keys []interface{}) ([]reflect.Value, []map[string]interface{}, error) {
+	var related []reflect.Value
+	var rows []map[string]interface{}
+
+	size := e.Dialect().MaxPlaceholders()
+	for start := 0; start < len(keys); start += size {
+		end := start + size
+		if end > len(keys) {
+			end = len(keys)
+		}
+		chunk := keys[start:end]
+
+		sql := sqlbuilder.Select(e, e.Dialect())
+		if rel.Type == model.ManyToMany {
+			sql.Select("t.*", "j.{"+rel.JoinCol+"} AS "+joinKeyName).
+				From("{#"+rm.Name+"} t").
+				Join("INNER", "{#"+rel.JoinTable+"} j", "j.{"+rel.JoinRefCol+"}=t.{"+rm.PK[0].Name+"}")
+		} else {
+			sql.Select("*").From("{#" + rm.Name + "}")
+		}
+		sql.WhereStmt().AndIn(refCol, chunk...)
+		whereNotDeleted(ctx, sql, rm)
+
+		r, err := sql.QueryContext(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		mapped, err := fetch.Map(false, r)
+		r.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, row := range mapped {
+			obj := reflect.New(rel.GoType)
+			if _, err = fetch.ObjFromMap(obj.Interface(), []map[string]interface{}{row}); err != nil {
+				return nil, nil, err
+			}
+
+			if h, ok := obj.Interface().(AfterFinder); ok {
+				if err = h.AfterFind(e); err != nil {
+					return nil, nil, err
+				}
+			}
+
+			related = append(related, obj.Elem())
+			rows = append(rows, row)
+		}
+	}
+
+	return related, rows, nil
+}
+
+// 将关联数据 r 转换成类型 t，t 可以是结构体或是结构体指针。
+func relationValue(t reflect.Type, r reflect.Value) reflect.Value {
+	if t.Kind() == reflect.Ptr {
+		return r.Addr()
+	}
+	return r
+}
+
+// 将关联列的值转换成可比较的字符串，
+// 数据库返回的值与结构体中的值类型可能并不相同，比如 int64 和 int。
+func relationKey(v interface{}) string {
+	if bs, ok := v.([]byte); ok {
+		return string(bs)
+	}
+	return fmt.Sprint(v)
+}
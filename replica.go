@@ -0,0 +1,83 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+type contextKey int
+
+const primaryKey contextKey = 0
+
+// Replica 表示一个只读的从数据库
+type Replica struct {
+	DB *sql.DB
+
+	// 权重，值越大，被选中的机会越多。小于等于 0 时按 1 处理。
+	Weight int
+}
+
+// NewDBWithReplicas 从一个主数据库和多个从数据库构建一个 DB 实例。
+//
+// DB.Query()、DB.Select()、DB.Count() 以及由 SelectStmt 发起的查询，
+// 会按权重轮流在各个从数据库上执行；而 DB.Exec()、DB.Prepare()、
+// 其它的 Model 写操作以及所有的事务操作，都将在主数据库上执行。
+//
+// 若需要读取刚写入的数据，可以通过 WithPrimary() 强制在主数据库上执行查询。
+func NewDBWithReplicas(primary *sql.DB, replicas []*Replica, tablePrefix string, dialect Dialect) (*DB, error) {
+	db, err := NewDBWithStdDB(primary, tablePrefix, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range replicas {
+		if r.DB == nil {
+			return nil, errors.New("从数据库不能为空")
+		}
+
+		weight := r.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+
+		for i := 0; i < weight; i++ {
+			db.replicas = append(db.replicas, r.DB)
+		}
+	}
+
+	return db, nil
+}
+
+// WithPrimary 返回一个新的 context，使用该 context 的查询会强制在主数据库上执行。
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// 获取用于执行查询的数据库实例
+//
+// 仅 SELECT 语句会在从数据库上执行，其它语句，
+// 比如带 RETURNING 子句的 INSERT 语句，依然在主数据库上执行。
+func (db *DB) readDB(ctx context.Context, query string) *sql.DB {
+	if len(db.replicas) == 0 || !isSelect(query) {
+		return db.stdDB
+	}
+
+	if primary, ok := ctx.Value(primaryKey).(bool); ok && primary {
+		return db.stdDB
+	}
+
+	i := atomic.AddUint32(&db.replicaNext, 1)
+	return db.replicas[int(i%uint32(len(db.replicas)))]
+}
+
+func isSelect(query string) bool {
+	query = strings.TrimLeft(query, " \t\r\n(")
+	return len(query) >= 6 && strings.EqualFold(query[:6], "SELECT")
+}
@@ -0,0 +1,77 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/issue9/assert"
+	"github.com/issue9/orm"
+	"github.com/issue9/orm/dialect"
+	"github.com/issue9/orm/internal/modeltest"
+)
+
+func TestNewDBWithReplicas(t *testing.T) {
+	a := assert.New(t)
+
+	const (
+		primaryDSN = "./orm_primary.db"
+		replicaDSN = "./orm_replica.db"
+	)
+
+	primary, err := sql.Open("sqlite3", primaryDSN)
+	a.NotError(err)
+	replica, err := sql.Open("sqlite3", replicaDSN)
+	a.NotError(err)
+
+	db, err := orm.NewDBWithReplicas(primary, []*orm.Replica{&orm.Replica{DB: replica, Weight: 2}}, prefix, dialect.Sqlite3())
+	a.NotError(err).NotNil(db)
+	defer func() {
+		a.NotError(db.Close())
+		a.NotError(os.Remove(primaryDSN))
+		a.NotError(os.Remove(replicaDSN))
+	}()
+
+	// 主从数据库分别建立表结构
+	a.NotError(db.Create(&modeltest.User{}))
+	rdb, err := orm.NewDBWithStdDB(replica, prefix, dialect.Sqlite3())
+	a.NotError(err)
+	a.NotError(rdb.Create(&modeltest.User{}))
+
+	// 写入主数据库
+	_, err = db.Insert(&modeltest.User{Username: "u1"})
+	a.NotError(err)
+
+	// 查询在从数据库上执行，无数据
+	cnt, err := db.Count(&modeltest.User{Username: "u1"})
+	a.NotError(err).Equal(0, cnt)
+
+	// 强制在主数据库上执行
+	ctx := orm.WithPrimary(context.Background())
+	cnt, err = db.CountContext(ctx, &modeltest.User{Username: "u1"})
+	a.NotError(err).Equal(1, cnt)
+
+	// 非 SELECT 语句，即使通过 Query 执行，也在主数据库上执行
+	rows, err := db.Query("INSERT INTO #users({username},{password}) VALUES(?,?) RETURNING {id}", "u2", "p2")
+	a.NotError(err)
+	a.True(rows.Next())
+	a.NotError(rows.Close())
+	cnt, err = db.CountContext(ctx, &modeltest.User{Username: "u2"})
+	a.NotError(err).Equal(1, cnt)
+
+	// 事务总是在主数据库上执行
+	tx, err := db.Begin()
+	a.NotError(err)
+	cnt, err = tx.Count(&modeltest.User{Username: "u1"})
+	a.NotError(err).Equal(1, cnt)
+	a.NotError(tx.Commit())
+
+	// 空的从数据库
+	db2, err := orm.NewDBWithReplicas(primary, []*orm.Replica{&orm.Replica{}}, prefix, dialect.Sqlite3())
+	a.Error(err).Nil(db2)
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/issue9/orm/model"
+	"github.com/issue9/orm/sqlbuilder"
+)
+
+const unscopedKey contextKey = 1
+
+// Unscoped 返回一个新的 context，使用该 context 的操作将忽略软删除的设置：
+// Select 和 Count 等操作会包含已经被软删除的数据，
+// Delete 则会直接从数据库中删除数据。
+func Unscoped(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unscopedKey, true)
+}
+
+func isUnscoped(ctx context.Context) bool {
+	unscoped, ok := ctx.Value(unscopedKey).(bool)
+	return ok && unscoped
+}
+
+// 为 sql 添加过滤掉已经被软删除数据的条件。
+func whereNotDeleted(ctx context.Context, sql sqlbuilder.WhereStmter, m *model.Model) {
+	if m.SoftDelete == nil || isUnscoped(ctx) {
+		return
+	}
+
+	sql.WhereStmt().And("{" + m.SoftDelete.Name + "} IS NULL")
+}
+
+// 将 rval 对应的数据标记为已删除。
+//
+// 若 rval 可写，则同时更新其软删除列的值。
+func softDel(ctx context.Context, e Engine, m *model.Model, rval reflect.Value) (sql.Result, error) {
+	now := currentTime(e)
+
+	sql := sqlbuilder.Update(e).
+		Table("{#"+m.Name+"}").
+		Set("{"+m.SoftDelete.Name+"}", now)
+	if err := where(sql, m, rval); err != nil {
+		return nil, err
+	}
+	whereNotDeleted(ctx, sql, m)
+
+	r, err := sql.ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if field := rval.FieldByName(m.SoftDelete.GoName); field.CanSet() {
+		field.Set(reflect.ValueOf(now))
+	}
+
+	return r, nil
+}
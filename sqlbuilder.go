@@ -5,10 +5,13 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"time"
 
 	"github.com/issue9/orm/model"
 	"github.com/issue9/orm/sqlbuilder"
@@ -28,43 +31,42 @@ func getModel(v interface{}) (*model.Model, reflect.Value, error) {
 	return m, rval, nil
 }
 
-// 根据 model 中的主键或是唯一索引为 sql 产生 where 语句，
-// 若两者都不存在，则返回错误信息。rval 为 struct 的 reflect.Value
-func where(sql sqlbuilder.WhereStmter, m *model.Model, rval reflect.Value) error {
-	vals := make([]interface{}, 0, 3)
-	keys := make([]string, 0, 3)
-
-	// 获取构成 where 的键名和键值
-	getKV := func(cols []*model.Column) bool {
+// 获取 rval 中可以唯一确定一条记录的列，即值都不为零值的主键或是唯一约束列，
+// 优先使用主键。若两者都不存在，则返回 nil。rval 为 struct 的 reflect.Value
+func keyColumns(m *model.Model, rval reflect.Value) []*model.Column {
+	isValid := func(cols []*model.Column) bool {
 		for _, col := range cols {
 			field := rval.FieldByName(col.GoName)
-
 			if !field.IsValid() || col.Zero == field.Interface() {
-				vals = vals[:0]
-				keys = keys[:0]
 				return false
 			}
-
-			keys = append(keys, col.Name)
-			vals = append(vals, field.Interface())
 		}
-		return len(keys) > 0 // 如果 keys 中有数据，表示已经采集成功，否则表示 cols 的长度为 0
+		return len(cols) > 0
 	}
 
-	if !getKV(m.PK) { // 没有主键，则尝试唯一约束
-		for _, cols := range m.UniqueIndexes {
-			if getKV(cols) {
-				break
-			}
+	if isValid(m.PK) {
+		return m.PK
+	}
+
+	for _, cols := range m.UniqueIndexes { // 没有主键，则尝试唯一约束
+		if isValid(cols) {
+			return cols
 		}
 	}
 
-	if len(keys) == 0 {
+	return nil
+}
+
+// 根据 model 中的主键或是唯一索引为 sql 产生 where 语句，
+// 若两者都不存在，则返回错误信息。rval 为 struct 的 reflect.Value
+func where(sql sqlbuilder.WhereStmter, m *model.Model, rval reflect.Value) error {
+	cols := keyColumns(m, rval)
+	if len(cols) == 0 {
 		return fmt.Errorf("没有主键或唯一约束，无法为 %s 产生 where 部分语句", m.Name)
 	}
 
-	for index, key := range keys {
-		sql.WhereStmt().And("{"+key+"}=?", vals[index])
+	for _, col := range cols {
+		sql.WhereStmt().And("{"+col.Name+"}=?", rval.FieldByName(col.GoName).Interface())
 	}
 
 	return nil
@@ -72,33 +74,32 @@ func where(sql sqlbuilder.WhereStmter, m *model.Model, rval reflect.Value) error
 
 // 根据 rval 中任意非零值产生 where 语句
 func whereAny(sql sqlbuilder.WhereStmter, m *model.Model, rval reflect.Value) error {
-	vals := make([]interface{}, 0, 3)
-	keys := make([]string, 0, 3)
+	if whereNotZero(sql, m, rval) == 0 {
+		return fmt.Errorf("没有非零值字段，无法为 %s 产生 where 部分语句", m.Name)
+	}
+
+	return nil
+}
 
-	for _, col := range m.Cols {
+// 根据 rval 中所有的非零值产生 where 语句，返回添加的条件数量。
+func whereNotZero(sql sqlbuilder.WhereStmter, m *model.Model, rval reflect.Value) int {
+	cnt := 0
+	for _, col := range m.Columns {
 		field := rval.FieldByName(col.GoName)
 
 		if !field.IsValid() || col.Zero == field.Interface() {
 			continue
 		}
 
-		keys = append(keys, col.Name)
-		vals = append(vals, field.Interface())
+		sql.WhereStmt().And("{"+col.Name+"}=?", field.Interface())
+		cnt++
 	}
 
-	if len(keys) == 0 {
-		return fmt.Errorf("没有非零值字段，无法为 %s 产生 where 部分语句", m.Name)
-	}
-
-	for index, key := range keys {
-		sql.WhereStmt().And("{"+key+"}=?", vals[index])
-	}
-
-	return nil
+	return cnt
 }
 
 // 统计符合 v 条件的记录数量。
-func count(e Engine, v interface{}) (int64, error) {
+func count(ctx context.Context, e Engine, v interface{}) (int64, error) {
 	m, rval, err := getModel(v)
 	if err != nil {
 		return 0, err
@@ -108,15 +109,16 @@ func count(e Engine, v interface{}) (int64, error) {
 	if err = whereAny(sql, m, rval); err != nil {
 		return 0, err
 	}
+	whereNotDeleted(ctx, sql, m)
 
-	return sql.QueryInt("count")
+	return sql.QueryIntContext(ctx, "count")
 }
 
 // 创建表。
 //
 // 部分数据库可能并没有提供在 CREATE TABLE 中直接指定 index 约束的功能。
 // 所以此处把创建表和创建索引分成两步操作。
-func create(e Engine, v interface{}) error {
+func create(ctx context.Context, e Engine, v interface{}) error {
 	m, _, err := getModel(v)
 	if err != nil {
 		return err
@@ -128,7 +130,7 @@ func create(e Engine, v interface{}) error {
 	}
 
 	for _, sql := range sqls {
-		if _, err := e.Exec(sql); err != nil {
+		if _, err := e.ExecContext(ctx, sql); err != nil {
 			return err
 		}
 	}
@@ -137,62 +139,273 @@ func create(e Engine, v interface{}) error {
 }
 
 // 删除一张表。
-func drop(e Engine, v interface{}) error {
+func drop(ctx context.Context, e Engine, v interface{}) error {
 	m, err := model.New(v)
 	if err != nil {
 		return err
 	}
 
-	_, err = sqlbuilder.DropTable(e).Table("{#" + m.Name + "}").Exec()
+	_, err = sqlbuilder.DropTable(e).Table("{#" + m.Name + "}").ExecContext(ctx)
 	return err
 }
 
 // 清空表，并重置 AI 计数。
-func truncate(e Engine, v interface{}) error {
+func truncate(ctx context.Context, e Engine, v interface{}) error {
 	m, err := model.New(v)
 	if err != nil {
 		return err
 	}
 
-	sql := sqlbuilder.Truncate(e, e.Dialect()).Table("#" + m.Name)
+	sql := sqlbuilder.Truncate(e, e.Dialect()).Table("{" + tablePrefix(e) + m.Name + "}")
 	if m.AI != nil {
 		sql.AI("{" + m.AI.Name + "}")
 	}
 
-	_, err = sql.Exec()
+	_, err = sql.ExecContext(ctx)
 	return err
 }
 
-func insert(e Engine, v interface{}) (sql.Result, error) {
-	m, rval, err := getModel(v)
+func insert(ctx context.Context, e Engine, v interface{}) (sql.Result, error) {
+	if h, ok := v.(BeforeInserter); ok {
+		if err := h.BeforeInsert(e); err != nil {
+			return nil, err
+		}
+	}
+
+	stmt, m, rval, err := buildInsertSQL(e, v)
+	if err != nil {
+		return nil, err
+	}
+
+	var r sql.Result
+	if m.AI == nil {
+		r, err = stmt.ExecContext(ctx)
+	} else {
+		r, err = insertAI(ctx, e, stmt, m, rval)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if h, ok := v.(AfterInserter); ok {
+		if err = h.AfterInsert(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// 执行插入语句，并将自增列的值写入 rval。
+//
+// 若能通过 sql.Result.LastInsertId() 获取自增列的值，则直接返回驱动的 sql.Result，
+// 否则返回由自增列的值构建的 sql.Result。
+func insertAI(ctx context.Context, e Engine, stmt *sqlbuilder.InsertStmt, m *model.Model, rval reflect.Value) (sql.Result, error) {
+	col := "{" + m.AI.Name + "}"
+
+	if idSQL, _ := e.Dialect().LastInsertIDSQL("{#"+m.Name+"}", col); idSQL == "" {
+		r, err := stmt.ExecContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := r.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		setAI(m, rval, id)
+		return r, nil
+	}
+
+	id, err := stmt.LastInsertIDContext(ctx, e.Dialect(), col)
 	if err != nil {
 		return nil, err
 	}
+	setAI(m, rval, id)
+	return returningResult(id), nil
+}
+
+// 通过 RETURNING 等语句获取自增列的值时，驱动不会返回 sql.Result，
+// 只能由自增列的值构建，能获取到该值，说明插入了一行数据。
+type returningResult int64
+
+func (r returningResult) LastInsertId() (int64, error) {
+	return int64(r), nil
+}
+
+func (r returningResult) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+// 获取列 col 在插入时的值，若返回的 ok 为 false，表示该列不需要插入。
+//
+// created 和 updated 列在零值时会被填充为 now。
+func insertValue(m *model.Model, col *model.Column, field reflect.Value, now time.Time) (val interface{}, ok bool) {
+	val = field.Interface()
+	if col.Zero != val {
+		return val, true
+	}
+
+	if col == m.Created || col == m.Updated {
+		return setTimestamp(col, field, now), true
+	}
+
+	// 在为零值的情况下，若该列是 AI、软删除列或是有默认值，则过滤掉。无论该零值是否为手动设置的。
+	if col.IsAI() || col.HasDefault || col == m.SoftDelete {
+		return nil, false
+	}
+
+	return val, true
+}
+
+// 获取 e 所在的 DB 的当前时间
+func currentTime(e Engine) time.Time {
+	switch v := e.(type) {
+	case *DB:
+		return v.now()
+	case *Tx:
+		return v.db.now()
+	default:
+		return time.Now()
+	}
+}
+
+// 获取 e 的表名前缀
+func tablePrefix(e Engine) string {
+	switch v := e.(type) {
+	case *DB:
+		return v.tablePrefix
+	case *Tx:
+		return v.db.tablePrefix
+	default:
+		return ""
+	}
+}
+
+// 将时间 t 转换成与列 col 相同类型的值，同时写入到 field 中，field 不可写时，忽略。
+//
+// 整数类型的列，保存的是 unix 时间戳。
+func setTimestamp(col *model.Column, field reflect.Value, t time.Time) interface{} {
+	val := reflect.ValueOf(t)
+	if col.GoType.Kind() != reflect.Struct {
+		val = reflect.ValueOf(t.Unix()).Convert(col.GoType)
+	}
+
+	if field.CanSet() {
+		field.Set(val)
+	}
+	return val.Interface()
+}
+
+// 将 rval 中乐观锁列的值加 1，与数据库中的值保持一致，rval 不可写时，忽略。
+func increaseOCC(m *model.Model, rval reflect.Value) {
+	field := rval.FieldByName(m.OCC.GoName)
+	if !field.CanSet() {
+		return
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(field.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(field.Uint() + 1)
+	}
+}
+
+// 将自增列的值 id 写入到 rval 中，rval 不可写时，忽略。
+func setAI(m *model.Model, rval reflect.Value, id int64) {
+	field := rval.FieldByName(m.AI.GoName)
+	if !field.CanSet() {
+		return
+	}
 
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		field.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.SetUint(uint64(id))
+	}
+}
+
+// 根据 v 生成插入语句
+func buildInsertSQL(e Engine, v interface{}) (*sqlbuilder.InsertStmt, *model.Model, reflect.Value, error) {
+	m, rval, err := getModel(v)
+	if err != nil {
+		return nil, nil, reflect.Value{}, err
+	}
+
+	now := currentTime(e)
 	sql := sqlbuilder.Insert(e).Table("{#" + m.Name + "}")
-	for name, col := range m.Cols {
+	for _, col := range m.Columns {
 		field := rval.FieldByName(col.GoName)
 		if !field.IsValid() {
-			return nil, fmt.Errorf("未找到该名称 %s 的值", col.GoName)
+			return nil, nil, reflect.Value{}, fmt.Errorf("未找到该名称 %s 的值", col.GoName)
 		}
 
-		// 在为零值的情况下，若该列是 AI 或是有默认值，则过滤掉。无论该零值是否为手动设置的。
-		if col.Zero == field.Interface() &&
-			(col.IsAI() || col.HasDefault) {
+		val, ok := insertValue(m, col, field, now)
+		if !ok {
 			continue
 		}
 
-		sql.KeyValue("{"+name+"}", field.Interface())
+		sql.KeyValue("{"+col.Name+"}", val)
+	}
+
+	return sql, m, rval, nil
+}
+
+// 插入数据，若与已有数据冲突，则更新已有数据。
+//
+// 以 v 中的主键或是唯一约束作为冲突检测的列；
+// cols 为冲突时需要更新的列，若未指定，则更新除冲突检测列之外的所有插入列。
+func upsert(ctx context.Context, e Engine, v interface{}, cols ...string) (sql.Result, error) {
+	sql, m, rval, err := buildInsertSQL(e, v)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := keyColumns(m, rval)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("没有主键或唯一约束，无法为 %s 产生冲突检测的列", m.Name)
+	}
+
+	target := make([]string, 0, len(keys))
+	for _, col := range keys {
+		target = append(target, "{"+col.Name+"}")
+	}
+
+	update := make([]string, 0, len(cols))
+	for _, col := range cols {
+		update = append(update, "{"+col+"}")
+	}
+
+	switch {
+	case len(update) > 0 && m.Updated != nil && !inStrSlice(m.Updated.Name, cols):
+		update = append(update, "{"+m.Updated.Name+"}")
+	case len(update) == 0 && m.Created != nil: // 冲突时不应该更新创建时间
+		for _, col := range m.Columns {
+			if col == m.Created || inColumns(col, keys) {
+				continue
+			}
+
+			// 与 insertValue() 相同，过滤掉未插入的列
+			field := rval.FieldByName(col.GoName)
+			if col.Zero == field.Interface() &&
+				(col.IsAI() || col.HasDefault || col == m.SoftDelete) {
+				continue
+			}
+
+			update = append(update, "{"+col.Name+"}")
+		}
 	}
 
-	return sql.Exec()
+	return sql.OnConflict(e.Dialect(), target, update...).ExecContext(ctx)
 }
 
 // 查找数据。
 //
 // 根据 v 的 pk 或中唯一索引列查找一行数据，并赋值给 v。
 // 若 v 为空，则不发生任何操作，v 可以是数组。
-func find(e Engine, v interface{}) error {
+func find(ctx context.Context, e Engine, v interface{}) error {
 	m, rval, err := getModel(v)
 	if err != nil {
 		return err
@@ -204,13 +417,110 @@ func find(e Engine, v interface{}) error {
 	if err = where(sql, m, rval); err != nil {
 		return err
 	}
+	whereNotDeleted(ctx, sql, m)
 
-	_, err = sql.QueryObj(v)
-	return err
+	return fetchObj(ctx, e, sql, v, m, rval)
+}
+
+// 查找所有符合 example 条件的数据，并写入到 objs 中。
+//
+// 仅在 opts.Count 为 true 时，返回符合条件的记录总数。
+func findMany(ctx context.Context, e Engine, example, objs interface{}, opts *FindOptions) (int64, error) {
+	m, rval, err := getModel(example)
+	if err != nil {
+		return 0, err
+	}
+
+	if opts == nil {
+		opts = &FindOptions{}
+	}
+
+	sql := sqlbuilder.Select(e, e.Dialect()).
+		Select("*").
+		From("{#" + m.Name + "}")
+	whereNotZero(sql, m, rval)
+	whereNotDeleted(ctx, sql, m)
+
+	// 在指定排序和分页之前查询总数，未指定 Count 时，返回实际读取的记录数量
+	var total int64
+	if opts.Count {
+		if total, err = sql.Count("COUNT(*) AS count").QueryIntContext(ctx, "count"); err != nil {
+			return 0, err
+		}
+		sql.Count("")
+	}
+
+	for _, order := range opts.Order {
+		name := strings.TrimPrefix(order, "-")
+		if _, found := m.Cols[name]; !found {
+			return 0, fmt.Errorf("不存在的列名 %s", name)
+		}
+
+		if name == order {
+			sql.Asc("{" + name + "}")
+		} else {
+			sql.Desc("{" + name + "}")
+		}
+	}
+
+	if opts.Limit > 0 {
+		sql.Limit(opts.Limit, opts.Offset)
+	}
+
+	cnt, _, _, err := sql.QueryObjContext(ctx, objs)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = afterFindMany(e, objs, cnt); err != nil {
+		return 0, err
+	}
+
+	if err = preload(ctx, e, objs, opts.Preload); err != nil {
+		return 0, err
+	}
+
+	if !opts.Count {
+		total = int64(cnt)
+	}
+	return total, nil
+}
+
+// 对 objs 中的前 cnt 个元素调用 AfterFinder 接口
+func afterFindMany(e Engine, objs interface{}, cnt int) error {
+	rval := reflect.ValueOf(objs)
+	for rval.Kind() == reflect.Ptr {
+		rval = rval.Elem()
+	}
+	if rval.Kind() != reflect.Slice && rval.Kind() != reflect.Array {
+		return nil
+	}
+
+	for i := 0; i < cnt && i < rval.Len(); i++ {
+		if h, ok := elem(rval, i).Interface().(AfterFinder); ok {
+			if err := h.AfterFind(e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// 获取数组 rval 的第 i 个元素
+//
+// 元素为可寻址的值类型时，返回其指针，这样才能调用指针接收者的钩子方法，
+// 写入的自增列等值也能反映到原数组中。
+func elem(rval reflect.Value, i int) reflect.Value {
+	item := rval.Index(i)
+	if item.Kind() != reflect.Ptr && item.CanAddr() {
+		item = item.Addr()
+	}
+	return item
 }
 
 // for update 只能作用于事务
-func forUpdate(tx *Tx, v interface{}) error {
+func forUpdate(ctx context.Context, tx *Tx, v interface{}) error {
 	m, rval, err := getModel(v)
 	if err != nil {
 		return err
@@ -223,41 +533,86 @@ func forUpdate(tx *Tx, v interface{}) error {
 	if err = where(sql, m, rval); err != nil {
 		return err
 	}
+	whereNotDeleted(ctx, sql, m)
 
-	_, err = sql.QueryObj(v)
-	return err
+	return fetchObj(ctx, tx, sql, v, m, rval)
+}
+
+// 将 sql 查询的结果导出到 v 中，并在找到数据时保存快照和调用 AfterFinder 接口。
+func fetchObj(ctx context.Context, e Engine, sql *sqlbuilder.SelectStmt, v interface{}, m *model.Model, rval reflect.Value) error {
+	cnt, _, _, err := sql.QueryObjContext(ctx, v)
+	if err != nil || cnt == 0 {
+		return err
+	}
+
+	takeSnapshot(v, m, rval)
+
+	if h, ok := v.(AfterFinder); ok {
+		return h.AfterFind(e)
+	}
+
+	return nil
 }
 
 // 更新 v 到数据库，默认情况下不更新零值。
 // cols 表示必须要更新的列，即使是零值。
+// 若 v 实现了 Tracker 接口且存在快照，则仅更新与快照不同的列。
 //
 // 更新依据为每个对象的主键或是唯一索引列。
 // 若不存在此两个类型的字段，则返回错误信息。
-func update(e Engine, v interface{}, cols ...string) (sql.Result, error) {
+func update(ctx context.Context, e Engine, v interface{}, cols ...string) (sql.Result, error) {
+	if h, ok := v.(BeforeUpdater); ok {
+		if err := h.BeforeUpdate(e); err != nil {
+			return nil, err
+		}
+	}
+
 	m, rval, err := getModel(v)
 	if err != nil {
 		return nil, err
 	}
 
+	snapshot := getSnapshot(v)
 	sql := sqlbuilder.Update(e).Table("{#" + m.Name + "}")
 	var occValue interface{}
-	for name, col := range m.Cols {
+	var updated reflect.Value
+	changed := false
+	for _, col := range m.Columns {
 		field := rval.FieldByName(col.GoName)
 		if !field.IsValid() {
 			return nil, fmt.Errorf("未找到该名称 %s 的值", col.GoName)
 		}
 
-		// 零值，但是不属于指定需要更新的列
-		if !inStrSlice(name, cols) && col.Zero == field.Interface() {
+		if m.Updated == col { // 无论是否指定，都更新为当前时间
+			updated = field
 			continue
 		}
 
-		if m.OCC == col { // 乐观锁
+		if m.OCC == col { // 乐观锁，零值也是有效的版本号
 			occValue = field.Interface()
 			continue
-		} else {
-			sql.Set("{"+name+"}", field.Interface())
 		}
+
+		if !inStrSlice(col.Name, cols) {
+			if snapshot != nil { // 与快照相同，未被修改
+				if sameValue(field.Interface(), snapshot[col.Name]) {
+					continue
+				}
+			} else if col.Zero == field.Interface() { // 零值，但是不属于指定需要更新的列
+				continue
+			}
+		}
+
+		sql.Set("{"+col.Name+"}", field.Interface())
+		changed = true
+	}
+
+	if snapshot != nil && !changed {
+		return nil, ErrNoChanges
+	}
+
+	if m.Updated != nil {
+		sql.Set("{"+m.Updated.Name+"}", setTimestamp(m.Updated, updated, currentTime(e)))
 	}
 
 	if m.OCC != nil {
@@ -268,7 +623,33 @@ func update(e Engine, v interface{}, cols ...string) (sql.Result, error) {
 		return nil, err
 	}
 
-	return sql.Exec()
+	r, err := sql.ExecContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.OCC != nil {
+		affected, err := r.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			return nil, ErrOCCConflict
+		}
+		increaseOCC(m, rval)
+	}
+
+	if snapshot != nil {
+		takeSnapshot(v, m, rval)
+	}
+
+	if h, ok := v.(AfterUpdater); ok {
+		if err = h.AfterUpdate(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
 }
 
 func inStrSlice(key string, slice []string) bool {
@@ -280,82 +661,267 @@ func inStrSlice(key string, slice []string) bool {
 	return false
 }
 
+func inColumns(col *model.Column, cols []*model.Column) bool {
+	for _, c := range cols {
+		if c == col {
+			return true
+		}
+	}
+	return false
+}
+
 // 将 v 生成 delete 的 sql 语句
-func del(e Engine, v interface{}) (sql.Result, error) {
+func del(ctx context.Context, e Engine, v interface{}) (sql.Result, error) {
+	if h, ok := v.(BeforeDeleter); ok {
+		if err := h.BeforeDelete(e); err != nil {
+			return nil, err
+		}
+	}
+
 	m, rval, err := getModel(v)
 	if err != nil {
 		return nil, err
 	}
 
+	var r sql.Result
+	if m.SoftDelete != nil && !isUnscoped(ctx) {
+		r, err = softDel(ctx, e, m, rval)
+	} else {
+		r, err = hardDel(ctx, e, m, rval)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if h, ok := v.(AfterDeleter); ok {
+		if err = h.AfterDelete(e); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// 从数据库中删除 rval 对应的数据
+func hardDel(ctx context.Context, e Engine, m *model.Model, rval reflect.Value) (sql.Result, error) {
 	sql := sqlbuilder.Delete(e).Table("{#" + m.Name + "}")
-	if err = where(sql, m, rval); err != nil {
+	if err := where(sql, m, rval); err != nil {
 		return nil, err
 	}
 
-	return sql.Exec()
+	return sql.ExecContext(ctx)
+}
+
+// InsertMany 拆分出的一条语句，包含 rval 中从 start 开始的 rows 个元素。
+type insertManyStmt struct {
+	*sqlbuilder.InsertStmt
+	start int
+	rows  int
+	ai    bool // 是否需要获取自增列的值，即自增列为零值，未被插入。
+}
+
+// 执行由 buildInsertManySQL 生成的语句。
+//
+// SQL 相同的语句会共用同一个预编译的语句；若启用了预编译语句的缓存，则直接使用缓存。
+// 无论是否通过预编译语句执行，参数都会经过转换，并触发相应的拦截器。
+//
+// 自增列为零值的元素，在插入之后会写入数据库生成的值。
+func execInsertMany(ctx context.Context, tx *Tx, stmts []*insertManyStmt, rval reflect.Value) error {
+	m, _, err := getModel(elem(rval, 0).Interface())
+	if err != nil {
+		return err
+	}
+
+	var idSQL string
+	var appendSQL bool
+	if m.AI != nil {
+		idSQL, appendSQL = tx.Dialect().LastInsertIDSQL("{#"+m.Name+"}", "{"+m.AI.Name+"}")
+	}
+
+	var prepared *sql.Stmt
+	var preparedQuery string
+	for _, stmt := range stmts {
+		query, args, err := stmt.SQL()
+		if err != nil {
+			return err
+		}
+
+		returning := stmt.ai && appendSQL && idSQL != ""
+		if returning {
+			query += idSQL
+		}
+
+		if prepared == nil && len(stmts) > 1 && tx.db.stmts == nil {
+			if prepared, err = tx.PrepareContext(ctx, query); err != nil {
+				return err
+			}
+			defer prepared.Close()
+			preparedQuery = query
+		}
+
+		if returning {
+			var rows *sql.Rows
+			if query == preparedQuery {
+				rows, err = tx.queryPrepared(ctx, prepared, query, args)
+			} else {
+				rows, err = tx.QueryContext(ctx, query, args...)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err = scanInsertIDs(rows, m, rval, stmt); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var r sql.Result
+		if query == preparedQuery {
+			r, err = tx.execPrepared(ctx, prepared, query, args)
+		} else {
+			r, err = tx.ExecContext(ctx, query, args...)
+		}
+		if err != nil {
+			return err
+		}
+
+		if stmt.ai && idSQL == "" {
+			if err = setInsertIDs(tx.Dialect(), r, m, rval, stmt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// 从 rows 中读取自增列的值，依次写入到 stmt 包含的元素中。
+func scanInsertIDs(rows *sql.Rows, m *model.Model, rval reflect.Value, stmt *insertManyStmt) error {
+	defer rows.Close()
+
+	for i := stmt.start; rows.Next() && i < stmt.start+stmt.rows; i++ {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+
+		_, irval, err := getModel(elem(rval, i).Interface())
+		if err != nil {
+			return err
+		}
+		setAI(m, irval, id)
+	}
+
+	return rows.Err()
 }
 
-// rval 为结构体指针组成的数据
-func buildInsertManySQL(e *Tx, rval reflect.Value) (*sqlbuilder.InsertStmt, error) {
-	sql := sqlbuilder.Insert(e)
-	keys := []string{}         // 保存列的顺序，方便后续元素获取值
+// 根据 r.LastInsertId() 推算 stmt 包含的各个元素的自增列值，并写入其中。
+//
+// 同一条语句插入的多行数据，其自增列的值是连续的，
+// 根据 Dialect.FirstInsertID() 可以确定 r.LastInsertId() 是第一行还是最后一行的值。
+func setInsertIDs(d Dialect, r sql.Result, m *model.Model, rval reflect.Value, stmt *insertManyStmt) error {
+	id, err := r.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if !d.FirstInsertID() {
+		id -= int64(stmt.rows - 1)
+	}
+
+	for i := 0; i < stmt.rows; i++ {
+		_, irval, err := getModel(elem(rval, stmt.start+i).Interface())
+		if err != nil {
+			return err
+		}
+		setAI(m, irval, id+int64(i))
+	}
+
+	return nil
+}
+
+// rval 为结构体或是结构体指针组成的非空数组
+//
+// 自增列、软删除列以及有默认值的列在零值时不会被插入，所以各元素需要插入的列可能并不相同，
+// 连续的、插入列相同的元素会放在同一组语句中，列不同时则新建一条语句；
+// 同时根据 Dialect.MaxPlaceholders() 的值限制每条语句包含的行数。
+func buildInsertManySQL(e *Tx, rval reflect.Value) ([]*insertManyStmt, error) {
+	now := currentTime(e)
+	stmts := make([]*insertManyStmt, 0, 1)
+	var curr *insertManyStmt   // 当前正在添加数据的语句
+	var cols []string          // 当前语句的列名
+	var size int               // 当前语句最多能包含的行数
 	var firstType reflect.Type // 记录数组中第一个元素的类型，保证后面的都相同
 
 	for i := 0; i < rval.Len(); i++ {
-		irval := rval.Index(i)
+		irval := elem(rval, i)
+
+		if h, ok := irval.Interface().(BeforeInserter); ok {
+			if err := h.BeforeInsert(e); err != nil {
+				return nil, err
+			}
+		}
 
 		m, irval, err := getModel(irval.Interface())
 		if err != nil {
 			return nil, err
 		}
 
-		if i == 0 { // 第一个元素，需要从中获取列信息。
+		if i == 0 {
 			firstType = irval.Type()
-			sql.Table("{#" + m.Name + "}")
-
-			for name, col := range m.Cols {
-				field := irval.FieldByName(col.GoName)
-				if !field.IsValid() {
-					return nil, fmt.Errorf("未找到该名称 %s 的值", col.GoName)
-				}
-
-				// 在为零值的情况下，若该列是 AI 或是有默认值，则过滤掉。无论该零值是否为手动设置的。
-				if col.Zero == field.Interface() &&
-					(col.IsAI() || col.HasDefault) {
-					continue
-				}
+		} else if firstType != irval.Type() { // 与第一个元素的类型不同。
+			return nil, errors.New("参数 v 中包含了不同类型的元素")
+		}
 
-				sql.KeyValue("{"+name+"}", field.Interface())
-				keys = append(keys, name)
-			}
-		} else { // 之后的元素，只需要获取其对应的值就行
-			if firstType != irval.Type() { // 与第一个元素的类型不同。
-				return nil, errors.New("参数 v 中包含了不同类型的元素")
+		ai := false // 自增列未被插入
+		rowCols := make([]string, 0, len(m.Columns))
+		vals := make([]interface{}, 0, len(m.Columns))
+		for _, col := range m.Columns {
+			field := irval.FieldByName(col.GoName)
+			if !field.IsValid() {
+				return nil, fmt.Errorf("未找到该名称 %s 的值", col.GoName)
 			}
 
-			vals := make([]interface{}, 0, len(keys))
-			for _, name := range keys {
-				col, found := m.Cols[name]
-				if !found {
-					return nil, fmt.Errorf("不存在的列名 %s", name)
-				}
+			val, ok := insertValue(m, col, field, now)
+			if !ok {
+				ai = ai || col.IsAI()
+				continue
+			}
 
-				field := irval.FieldByName(col.GoName)
-				if !field.IsValid() {
-					return nil, fmt.Errorf("未找到该名称 %s 的值", col.GoName)
-				}
+			rowCols = append(rowCols, "{"+col.Name+"}")
+			vals = append(vals, val)
+		}
 
-				// 在为零值的情况下，若该列是 AI 或是有默认值，则过滤掉。无论该零值是否为手动设置的。
-				if col.Zero == field.Interface() &&
-					(col.IsAI() || col.HasDefault) {
-					continue
-				}
+		if curr == nil || curr.rows >= size || !equalStrings(cols, rowCols) {
+			cols = rowCols
+			size = 1
+			if len(cols) > 0 && e.Dialect().MaxPlaceholders() > len(cols) {
+				size = e.Dialect().MaxPlaceholders() / len(cols)
+			}
 
-				vals = append(vals, field.Interface())
+			curr = &insertManyStmt{
+				InsertStmt: sqlbuilder.Insert(e).Table("{#" + m.Name + "}").Columns(cols...),
+				start:      i,
+				ai:         ai,
 			}
-			sql.Values(vals...)
+			stmts = append(stmts, curr)
 		}
+		curr.Values(vals...)
+		curr.rows++
 	} // end for array
 
-	return sql, nil
+	return stmts, nil
+}
+
+func equalStrings(s1, s2 []string) bool {
+	if len(s1) != len(s2) {
+		return false
+	}
+
+	for i, v := range s1 {
+		if v != s2[i] {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,215 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/issue9/orm/fetch"
+)
+
+// CompoundStmt 以 UNION、INTERSECT 等操作合并多条查询语句
+//
+// 各条查询语句中不应该包含 ORDER BY 和 LIMIT，
+// 部分数据库(比如 sqlite3)不支持这种语法，
+// 需要排序或是分页时，可以通过 CompoundStmt 自身的 Asc()、Desc() 和 Limit() 指定。
+type CompoundStmt struct {
+	engine  Engine
+	dialect Dialect
+
+	selects []*SelectStmt
+	ops     []string // ops[i] 为 selects[i] 与 selects[i+1] 之间的操作符
+
+	orders []*orderBy
+
+	limitQuery string
+	limitVals  []interface{}
+}
+
+// Compound 声明一条以 sel 开头的复合查询语句
+func Compound(e Engine, d Dialect, sel *SelectStmt) *CompoundStmt {
+	return &CompoundStmt{
+		engine:  e,
+		dialect: d,
+		selects: []*SelectStmt{sel},
+		ops:     make([]string, 0, 5),
+	}
+}
+
+// Union 以 UNION 合并 sel 的查询结果，重复的记录会被去除。
+func (stmt *CompoundStmt) Union(sel *SelectStmt) *CompoundStmt {
+	return stmt.append(" UNION ", sel)
+}
+
+// UnionAll 以 UNION ALL 合并 sel 的查询结果，保留所有的记录。
+func (stmt *CompoundStmt) UnionAll(sel *SelectStmt) *CompoundStmt {
+	return stmt.append(" UNION ALL ", sel)
+}
+
+// Intersect 以 INTERSECT 获取与 sel 的查询结果相同的记录
+func (stmt *CompoundStmt) Intersect(sel *SelectStmt) *CompoundStmt {
+	return stmt.append(" INTERSECT ", sel)
+}
+
+// Except 以 EXCEPT 去除 sel 的查询结果中存在的记录
+func (stmt *CompoundStmt) Except(sel *SelectStmt) *CompoundStmt {
+	return stmt.append(" EXCEPT ", sel)
+}
+
+func (stmt *CompoundStmt) append(op string, sel *SelectStmt) *CompoundStmt {
+	if len(stmt.selects) > 0 { // 第一条语句之前没有操作符
+		stmt.ops = append(stmt.ops, op)
+	}
+	stmt.selects = append(stmt.selects, sel)
+	return stmt
+}
+
+// Desc 倒序查询
+func (stmt *CompoundStmt) Desc(col ...string) *CompoundStmt {
+	stmt.orders = append(stmt.orders, &orderBy{cols: col, asc: false})
+	return stmt
+}
+
+// Asc 正序查询
+func (stmt *CompoundStmt) Asc(col ...string) *CompoundStmt {
+	stmt.orders = append(stmt.orders, &orderBy{cols: col, asc: true})
+	return stmt
+}
+
+// Limit 生成 SQL 的 Limit 语句
+func (stmt *CompoundStmt) Limit(limit interface{}, offset ...interface{}) *CompoundStmt {
+	query, vals := stmt.dialect.LimitSQL(limit, offset...)
+	stmt.limitQuery = query
+	stmt.limitVals = vals
+	return stmt
+}
+
+// Reset 重置语句
+//
+// 所有的查询语句都会被清除，之后通过 Union() 等函数添加的第一条语句，
+// 会作为复合语句的开头，其操作符会被忽略。
+func (stmt *CompoundStmt) Reset() {
+	stmt.selects = stmt.selects[:0]
+	stmt.ops = stmt.ops[:0]
+	stmt.orders = stmt.orders[:0]
+
+	stmt.limitQuery = ""
+	stmt.limitVals = nil
+}
+
+// SQL 获取 SQL 语句及对应的参数
+//
+// 各条查询语句的内容在调用 SQL() 时才会生成。
+func (stmt *CompoundStmt) SQL() (string, []interface{}, error) {
+	if len(stmt.selects) == 0 {
+		return "", nil, ErrValueIsEmpty
+	}
+
+	buf := New("")
+	args := make([]interface{}, 0, 10)
+
+	for i, sel := range stmt.selects {
+		query, vals, err := sel.SQL()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if i > 0 {
+			buf.WriteString(stmt.ops[i-1])
+		}
+		buf.WriteString(query)
+		args = append(args, vals...)
+	}
+
+	// order by
+	if len(stmt.orders) > 0 {
+		buf.WriteString(" ORDER BY ")
+		for _, order := range stmt.orders {
+			for _, c := range order.cols {
+				buf.WriteString(c)
+				buf.WriteByte(',')
+			}
+			buf.TruncateLast(1)
+
+			if order.asc {
+				buf.WriteString(" ASC,")
+			} else {
+				buf.WriteString(" DESC,")
+			}
+		}
+		buf.TruncateLast(1)
+	}
+
+	// limit
+	if stmt.limitQuery != "" {
+		buf.WriteString(stmt.limitQuery)
+		args = append(args, stmt.limitVals...)
+	}
+
+	return buf.String(), args, nil
+}
+
+// Prepare 预编译
+func (stmt *CompoundStmt) Prepare() (*sql.Stmt, error) {
+	return prepare(stmt.engine, stmt)
+}
+
+// PrepareContext 预编译
+func (stmt *CompoundStmt) PrepareContext(ctx context.Context) (*sql.Stmt, error) {
+	return prepareContext(ctx, stmt.engine, stmt)
+}
+
+// Query 查询
+func (stmt *CompoundStmt) Query() (*sql.Rows, error) {
+	return query(stmt.engine, stmt)
+}
+
+// QueryContext 查询
+func (stmt *CompoundStmt) QueryContext(ctx context.Context) (*sql.Rows, error) {
+	return queryContext(ctx, stmt.engine, stmt)
+}
+
+// QueryObj 将符合当前条件的所有记录依次写入 objs 中。
+//
+// 关于 objs 的值类型，可以参考 github.com/issue9/orm/fetch.Obj 函数的相关介绍。
+func (stmt *CompoundStmt) QueryObj(objs interface{}) (int, error) {
+	return stmt.QueryObjContext(context.Background(), objs)
+}
+
+// QueryObjContext 将符合当前条件的所有记录依次写入 objs 中。
+//
+// 关于 objs 的值类型，可以参考 github.com/issue9/orm/fetch.Obj 函数的相关介绍。
+func (stmt *CompoundStmt) QueryObjContext(ctx context.Context, objs interface{}) (int, error) {
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	return fetch.Obj(objs, rows)
+}
+
+// QueryInt 查询指定列的第一行数据，并将其转换成 int
+func (stmt *CompoundStmt) QueryInt(colName string) (int64, error) {
+	return stmt.QueryIntContext(context.Background(), colName)
+}
+
+// QueryIntContext 查询指定列的第一行数据，并将其转换成 int
+func (stmt *CompoundStmt) QueryIntContext(ctx context.Context, colName string) (int64, error) {
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cols, err := fetch.ColumnString(true, colName, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(cols[0], 10, 64)
+}
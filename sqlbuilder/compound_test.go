@@ -0,0 +1,97 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/issue9/orm"
+	"github.com/issue9/orm/dialect"
+	"github.com/issue9/orm/internal/sqltest"
+	"github.com/issue9/orm/sqlbuilder"
+
+	"github.com/issue9/assert"
+)
+
+var _ sqlbuilder.SQLer = &sqlbuilder.CompoundStmt{}
+
+func TestCompound(t *testing.T) {
+	a := assert.New(t)
+	d := dialect.Sqlite3()
+
+	s1 := sqlbuilder.Select(nil, d).Select("id", "name").From("users").Where("age>?", 18)
+	s2 := sqlbuilder.Select(nil, d).Select("id", "name").From("admins").Where("state=?", 1)
+	s3 := sqlbuilder.Select(nil, d).Select("id", "name").From("bans")
+
+	c := sqlbuilder.Compound(nil, d, s1).UnionAll(s2).Except(s3).Desc("id").Asc("name").Limit(10, 5)
+	query, args, err := c.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{18, 1, 10, 5})
+	sqltest.Equal(a, query, "select id,name from users where age>? union all select id,name from admins where state=? "+
+		"except select id,name from bans order by id desc,name asc limit ? offset ?")
+
+	c = sqlbuilder.Compound(nil, d, s1).Union(s2).Intersect(s3)
+	query, args, err = c.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{18, 1})
+	sqltest.Equal(a, query, "select id,name from users where age>? union select id,name from admins where state=? "+
+		"intersect select id,name from bans")
+
+	// 子查询出错
+	c.Union(sqlbuilder.Select(nil, d).Select("id"))
+	query, args, err = c.SQL()
+	a.Equal(err, sqlbuilder.ErrTableIsEmpty).Empty(query).Nil(args)
+
+	c.Reset()
+	query, args, err = c.SQL()
+	a.Error(err).Empty(query).Nil(args)
+
+	// Reset 之后添加的第一条语句作为开头
+	c.Union(s1).Except(s2).UnionAll(s3)
+	query, args, err = c.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{18, 1})
+	sqltest.Equal(a, query, "select id,name from users where age>? except select id,name from admins where state=? "+
+		"union all select id,name from bans")
+}
+
+func TestCompound_Query(t *testing.T) {
+	a := assert.New(t)
+	e, err := orm.NewDB("sqlite3", "./test.db", "test_", dialect.Sqlite3())
+	a.NotError(err)
+	defer func() {
+		_, err = e.Exec("DROP TABLE IF EXISTS #nums")
+		a.NotError(err)
+		a.NotError(e.Close())
+	}()
+
+	_, err = e.Exec("DROP TABLE IF EXISTS #nums")
+	a.NotError(err)
+	_, err = e.Exec("CREATE TABLE #nums({id} INTEGER NOT NULL)")
+	a.NotError(err)
+	for i := 1; i <= 5; i++ {
+		_, err = e.Exec("INSERT INTO #nums({id}) VALUES(?)", i)
+		a.NotError(err)
+	}
+
+	sel := func() *sqlbuilder.SelectStmt {
+		return sqlbuilder.Select(e, e.Dialect()).Select("{id}").From("#nums")
+	}
+	c := sqlbuilder.Compound(e, e.Dialect(), sel().Where("{id}<?", 3)).
+		Union(sel().Where("{id}>?", 3)).
+		Desc("id").
+		Limit(1)
+	id, err := c.QueryInt("id")
+	a.NotError(err).Equal(id, 5)
+
+	type num struct {
+		ID int64 `orm:"name(id)"`
+	}
+	nums := []*num{}
+	c = sqlbuilder.Compound(e, e.Dialect(), sel()).Except(sel().Where("{id}=?", 3)).Asc("id")
+	cnt, err := c.QueryObj(&nums)
+	a.NotError(err).Equal(cnt, 4)
+	a.Equal(nums[0].ID, 1).Equal(nums[3].ID, 5)
+}
@@ -0,0 +1,222 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/issue9/orm/fetch"
+)
+
+// Cursor 表示键集分页中某一条记录的位置，由该记录中各排序列的值组成。
+//
+// 可以通过 Encode() 编码成字符串交由客户端保存，之后再通过 ParseCursor() 还原。
+type Cursor struct {
+	values []interface{}
+}
+
+// NewCursor 声明一个 Cursor 实例
+//
+// values 为各排序列的值，顺序须与 Asc() 和 Desc() 指定的列顺序相同。
+func NewCursor(values ...interface{}) *Cursor {
+	return &Cursor{values: values}
+}
+
+// Values 返回游标中各排序列的值
+func (c *Cursor) Values() []interface{} {
+	return c.values
+}
+
+// Encode 将游标编码成字符串，编码后的内容可以直接用于 URL 中。
+func (c *Cursor) Encode() (string, error) {
+	items := make([][2]string, 0, len(c.values))
+
+	for _, v := range c.values {
+		var typ, val string
+
+		rval := reflect.ValueOf(v)
+		switch rval.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			typ, val = "i", strconv.FormatInt(rval.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			typ, val = "u", strconv.FormatUint(rval.Uint(), 10)
+		case reflect.Float32, reflect.Float64:
+			typ, val = "f", strconv.FormatFloat(rval.Float(), 'g', -1, 64)
+		case reflect.Bool:
+			typ, val = "b", strconv.FormatBool(rval.Bool())
+		case reflect.String:
+			typ, val = "s", rval.String()
+		default:
+			switch vv := v.(type) {
+			case []byte:
+				typ, val = "x", base64.StdEncoding.EncodeToString(vv)
+			case time.Time:
+				typ, val = "t", vv.Format(time.RFC3339Nano)
+			default:
+				return "", fmt.Errorf("游标不支持的类型 %T", v)
+			}
+		}
+
+		items = append(items, [2]string{typ, val})
+	}
+
+	data, err := json.Marshal(items)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// ParseCursor 从 Cursor.Encode() 生成的字符串中还原 Cursor 实例
+func ParseCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	items := [][2]string{}
+	if err = json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+
+	values := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		var v interface{}
+
+		switch item[0] {
+		case "i":
+			v, err = strconv.ParseInt(item[1], 10, 64)
+		case "u":
+			v, err = strconv.ParseUint(item[1], 10, 64)
+		case "f":
+			v, err = strconv.ParseFloat(item[1], 64)
+		case "b":
+			v, err = strconv.ParseBool(item[1])
+		case "s":
+			v = item[1]
+		case "x":
+			v, err = base64.StdEncoding.DecodeString(item[1])
+		case "t":
+			v, err = time.Parse(time.RFC3339Nano, item[1])
+		default:
+			err = errors.New("无效的游标内容")
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return NewCursor(values...), nil
+}
+
+// 从查询结果中获取 cols 各列的值，组成 Cursor 实例。
+//
+// row 为查询结果中的一行数据，obj 为由 row 导出的对象。
+// 列有对应的字段时，采用字段的值，使游标中的值与字段的类型相同，
+// 不受驱动返回的类型影响，比如 mysql 中的字符串会以 []byte 返回；
+// 否则采用 row 中的原始值。任意列不存在或是为 NULL 时，返回 nil。
+func newCursor(cols []string, obj reflect.Value, row map[string]interface{}) *Cursor {
+	fields, err := fetch.Fields(obj)
+	if err != nil { // 无法获取字段，则全部采用原始值
+		fields = nil
+	}
+
+	values := make([]interface{}, 0, len(cols))
+	for _, col := range cols {
+		name := cursorColumnName(col)
+		if v, found := row[name]; !found || v == nil {
+			return nil
+		}
+
+		if field, found := fields[name]; found {
+			values = append(values, field.Interface())
+		} else {
+			values = append(values, row[name])
+		}
+	}
+
+	return NewCursor(values...)
+}
+
+// 获取排序列在查询结果中的列名，去掉表名和引号等内容。
+func cursorColumnName(col string) string {
+	if index := strings.LastIndexByte(col, '.'); index >= 0 {
+		col = col[index+1:]
+	}
+
+	return strings.Trim(col, "{}`\"[] ")
+}
+
+// 生成位于游标之后的记录的查询条件。
+//
+// cols 为排序列，asc 为对应列是否为正序排列。
+func cursorCond(d Dialect, cols []string, asc []bool, values []interface{}) (string, []interface{}, error) {
+	if len(cols) == 0 || len(cols) != len(values) {
+		return "", nil, ErrCursorNotMatch
+	}
+
+	op := func(i int) string {
+		if asc[i] {
+			return ">"
+		}
+		return "<"
+	}
+
+	sameDirection := true
+	for _, a := range asc {
+		if a != asc[0] {
+			sameDirection = false
+			break
+		}
+	}
+
+	if len(cols) == 1 {
+		return cols[0] + op(0) + "?", values, nil
+	}
+
+	buf := New("")
+	args := make([]interface{}, 0, len(values))
+
+	// 所有列的排序方向相同，可以使用行值比较
+	if sameDirection && d != nil && d.RowValueComparison() {
+		buf.WriteByte('(')
+		for _, col := range cols {
+			buf.WriteString(col).WriteByte(',')
+		}
+		buf.TruncateLast(1).WriteString(")").WriteString(op(0)).WriteByte('(')
+		for range cols {
+			buf.WriteString("?,")
+		}
+		buf.TruncateLast(1).WriteByte(')')
+
+		return buf.String(), append(args, values...), nil
+	}
+
+	// (c1>?) OR (c1=? AND c2>?) OR ...
+	buf.WriteByte('(')
+	for i := range cols {
+		buf.WriteByte('(')
+		for j := 0; j < i; j++ {
+			buf.WriteString(cols[j]).WriteString("=? AND ")
+			args = append(args, values[j])
+		}
+		buf.WriteString(cols[i]).WriteString(op(i)).WriteString("?)")
+		args = append(args, values[i])
+
+		buf.WriteString(" OR ")
+	}
+	buf.TruncateLast(4).WriteByte(')')
+
+	return buf.String(), args, nil
+}
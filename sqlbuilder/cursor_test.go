@@ -0,0 +1,77 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/issue9/assert"
+)
+
+func TestCursor_Encode(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Date(2018, 1, 2, 3, 4, 5, 6, time.UTC)
+	c := NewCursor(int(-1), uint8(2), 1.5, true, "str", []byte("bytes"), now)
+	s, err := c.Encode()
+	a.NotError(err).NotEmpty(s)
+
+	c, err = ParseCursor(s)
+	a.NotError(err).NotNil(c)
+	vals := c.Values()
+	a.Equal(len(vals), 7)
+	a.Equal(vals[0], int64(-1)).
+		Equal(vals[1], uint64(2)).
+		Equal(vals[2], 1.5).
+		Equal(vals[3], true).
+		Equal(vals[4], "str").
+		Equal(vals[5], []byte("bytes"))
+	a.True(vals[6].(time.Time).Equal(now))
+
+	// 不支持的类型
+	s, err = NewCursor(struct{}{}).Encode()
+	a.Error(err).Empty(s)
+
+	// 无效的内容
+	c, err = ParseCursor("not base64!")
+	a.Error(err).Nil(c)
+	c, err = ParseCursor("W1sieiIsIjEiXV0") // [["z","1"]]
+	a.Error(err).Nil(c)
+}
+
+func TestCursorColumnName(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(cursorColumnName("id"), "id")
+	a.Equal(cursorColumnName("{id}"), "id")
+	a.Equal(cursorColumnName("{#users}.{id}"), "id")
+	a.Equal(cursorColumnName("u.`id`"), "id")
+}
+
+func TestNewCursor(t *testing.T) {
+	a := assert.New(t)
+
+	type user struct {
+		ID   int64  `orm:"name(id)"`
+		Name string `orm:"name(name)"`
+	}
+	u := &user{ID: 5, Name: "n5"}
+
+	// 采用字段的类型，而不是驱动返回的 []byte
+	row := map[string]interface{}{"id": []byte("5"), "name": []byte("n5"), "age": []byte("18")}
+	c := newCursor([]string{"{name}", "u.{id}"}, reflect.ValueOf(u), row)
+	a.NotNil(c).Equal(c.Values(), []interface{}{"n5", int64(5)})
+
+	// 没有对应的字段，采用原始值
+	c = newCursor([]string{"age"}, reflect.ValueOf(u), row)
+	a.NotNil(c).Equal(c.Values(), []interface{}{[]byte("18")})
+
+	// 排序列不在查询结果中，或是为 NULL
+	a.Nil(newCursor([]string{"created"}, reflect.ValueOf(u), row))
+	row["name"] = nil
+	a.Nil(newCursor([]string{"name"}, reflect.ValueOf(u), row))
+}
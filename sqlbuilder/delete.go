@@ -14,6 +14,8 @@ type DeleteStmt struct {
 	engine Engine
 	table  string
 	where  *WhereStmt
+
+	with withClause
 }
 
 // Delete 声明一条删除语句
@@ -36,18 +38,45 @@ func (stmt *DeleteStmt) SQL() (string, []interface{}, error) {
 		return "", nil, ErrTableIsEmpty
 	}
 
+	withQuery, withArgs, err := stmt.with.SQL()
+	if err != nil {
+		return "", nil, err
+	}
+
 	query, args, err := stmt.where.SQL()
 	if err != nil {
 		return "", nil, err
 	}
 
-	return "DELETE FROM " + stmt.table + " WHERE " + query, args, nil
+	if len(withArgs) > 0 {
+		args = append(withArgs, args...)
+	}
+
+	return withQuery + "DELETE FROM " + stmt.table + " WHERE " + query, args, nil
 }
 
 // Reset 重置语句
 func (stmt *DeleteStmt) Reset() {
 	stmt.table = ""
 	stmt.where.Reset()
+	stmt.with.reset()
+}
+
+// With 在语句之前添加一条名为 name 的公用表表达式，即 WITH name(cols) AS (query)。
+//
+// query 一般为 *SelectStmt 或是 *CompoundStmt，cols 为可选的列名。
+// 可以在 Where() 等条件中引用 name，比如 {id} IN(SELECT {id} FROM name)。
+func (stmt *DeleteStmt) With(name string, query SQLer, cols ...string) *DeleteStmt {
+	stmt.with.add(false, name, query, cols)
+	return stmt
+}
+
+// WithRecursive 添加一条递归的公用表表达式，生成的语句以 WITH RECURSIVE 开头。
+//
+// query 一般为以 UNION ALL 连接的 *CompoundStmt，其中的查询语句可以引用 name 本身。
+func (stmt *DeleteStmt) WithRecursive(name string, query SQLer, cols ...string) *DeleteStmt {
+	stmt.with.add(true, name, query, cols)
+	return stmt
 }
 
 // WhereStmt 实现 WhereStmter 接口
@@ -72,6 +101,174 @@ func (stmt *DeleteStmt) Or(cond string, args ...interface{}) *DeleteStmt {
 	return stmt
 }
 
+// AndIn 指定 where ... AND col IN(v...) 语句，具体说明可参考 WhereStmt.AndIn()
+func (stmt *DeleteStmt) AndIn(col string, v ...interface{}) *DeleteStmt {
+	stmt.where.AndIn(col, v...)
+	return stmt
+}
+
+// OrIn 指定 where ... OR col IN(v...) 语句，具体说明可参考 WhereStmt.OrIn()
+func (stmt *DeleteStmt) OrIn(col string, v ...interface{}) *DeleteStmt {
+	stmt.where.OrIn(col, v...)
+	return stmt
+}
+
+// AndNotIn 指定 where ... AND col NOT IN(v...) 语句，具体说明可参考 WhereStmt.AndNotIn()
+func (stmt *DeleteStmt) AndNotIn(col string, v ...interface{}) *DeleteStmt {
+	stmt.where.AndNotIn(col, v...)
+	return stmt
+}
+
+// OrNotIn 指定 where ... OR col NOT IN(v...) 语句，具体说明可参考 WhereStmt.OrNotIn()
+func (stmt *DeleteStmt) OrNotIn(col string, v ...interface{}) *DeleteStmt {
+	stmt.where.OrNotIn(col, v...)
+	return stmt
+}
+
+// AndExists 指定 where ... AND EXISTS(sub) 语句
+func (stmt *DeleteStmt) AndExists(sub *SelectStmt) *DeleteStmt {
+	stmt.where.AndExists(sub)
+	return stmt
+}
+
+// OrExists 指定 where ... OR EXISTS(sub) 语句
+func (stmt *DeleteStmt) OrExists(sub *SelectStmt) *DeleteStmt {
+	stmt.where.OrExists(sub)
+	return stmt
+}
+
+// AndNotExists 指定 where ... AND NOT EXISTS(sub) 语句
+func (stmt *DeleteStmt) AndNotExists(sub *SelectStmt) *DeleteStmt {
+	stmt.where.AndNotExists(sub)
+	return stmt
+}
+
+// OrNotExists 指定 where ... OR NOT EXISTS(sub) 语句
+func (stmt *DeleteStmt) OrNotExists(sub *SelectStmt) *DeleteStmt {
+	stmt.where.OrNotExists(sub)
+	return stmt
+}
+
+// AndBetween 指定 where ... AND col BETWEEN v1 AND v2 语句，具体说明可参考 WhereStmt.AndBetween()
+func (stmt *DeleteStmt) AndBetween(col string, v1, v2 interface{}) *DeleteStmt {
+	stmt.where.AndBetween(col, v1, v2)
+	return stmt
+}
+
+// OrBetween 指定 where ... OR col BETWEEN v1 AND v2 语句，具体说明可参考 WhereStmt.OrBetween()
+func (stmt *DeleteStmt) OrBetween(col string, v1, v2 interface{}) *DeleteStmt {
+	stmt.where.OrBetween(col, v1, v2)
+	return stmt
+}
+
+// AndLike 指定 where ... AND col LIKE pattern 语句，具体说明可参考 WhereStmt.AndLike()
+func (stmt *DeleteStmt) AndLike(col string, pattern string) *DeleteStmt {
+	stmt.where.AndLike(col, pattern)
+	return stmt
+}
+
+// OrLike 指定 where ... OR col LIKE pattern 语句，具体说明可参考 WhereStmt.OrLike()
+func (stmt *DeleteStmt) OrLike(col string, pattern string) *DeleteStmt {
+	stmt.where.OrLike(col, pattern)
+	return stmt
+}
+
+// AndIsNull 指定 where ... AND col IS NULL 语句，具体说明可参考 WhereStmt.AndIsNull()
+func (stmt *DeleteStmt) AndIsNull(col string) *DeleteStmt {
+	stmt.where.AndIsNull(col)
+	return stmt
+}
+
+// OrIsNull 指定 where ... OR col IS NULL 语句，具体说明可参考 WhereStmt.OrIsNull()
+func (stmt *DeleteStmt) OrIsNull(col string) *DeleteStmt {
+	stmt.where.OrIsNull(col)
+	return stmt
+}
+
+// AndNotNull 指定 where ... AND col IS NOT NULL 语句，具体说明可参考 WhereStmt.AndNotNull()
+func (stmt *DeleteStmt) AndNotNull(col string) *DeleteStmt {
+	stmt.where.AndNotNull(col)
+	return stmt
+}
+
+// OrNotNull 指定 where ... OR col IS NOT NULL 语句，具体说明可参考 WhereStmt.OrNotNull()
+func (stmt *DeleteStmt) OrNotNull(col string) *DeleteStmt {
+	stmt.where.OrNotNull(col)
+	return stmt
+}
+
+// AndEq 指定 where ... AND col=v 语句，具体说明可参考 WhereStmt.AndEq()
+func (stmt *DeleteStmt) AndEq(col string, v interface{}) *DeleteStmt {
+	stmt.where.AndEq(col, v)
+	return stmt
+}
+
+// OrEq 指定 where ... OR col=v 语句，具体说明可参考 WhereStmt.OrEq()
+func (stmt *DeleteStmt) OrEq(col string, v interface{}) *DeleteStmt {
+	stmt.where.OrEq(col, v)
+	return stmt
+}
+
+// AndNeq 指定 where ... AND col<>v 语句，具体说明可参考 WhereStmt.AndNeq()
+func (stmt *DeleteStmt) AndNeq(col string, v interface{}) *DeleteStmt {
+	stmt.where.AndNeq(col, v)
+	return stmt
+}
+
+// OrNeq 指定 where ... OR col<>v 语句，具体说明可参考 WhereStmt.OrNeq()
+func (stmt *DeleteStmt) OrNeq(col string, v interface{}) *DeleteStmt {
+	stmt.where.OrNeq(col, v)
+	return stmt
+}
+
+// AndGt 指定 where ... AND col>v 语句，具体说明可参考 WhereStmt.AndGt()
+func (stmt *DeleteStmt) AndGt(col string, v interface{}) *DeleteStmt {
+	stmt.where.AndGt(col, v)
+	return stmt
+}
+
+// OrGt 指定 where ... OR col>v 语句，具体说明可参考 WhereStmt.OrGt()
+func (stmt *DeleteStmt) OrGt(col string, v interface{}) *DeleteStmt {
+	stmt.where.OrGt(col, v)
+	return stmt
+}
+
+// AndGte 指定 where ... AND col>=v 语句，具体说明可参考 WhereStmt.AndGte()
+func (stmt *DeleteStmt) AndGte(col string, v interface{}) *DeleteStmt {
+	stmt.where.AndGte(col, v)
+	return stmt
+}
+
+// OrGte 指定 where ... OR col>=v 语句，具体说明可参考 WhereStmt.OrGte()
+func (stmt *DeleteStmt) OrGte(col string, v interface{}) *DeleteStmt {
+	stmt.where.OrGte(col, v)
+	return stmt
+}
+
+// AndLt 指定 where ... AND col<v 语句，具体说明可参考 WhereStmt.AndLt()
+func (stmt *DeleteStmt) AndLt(col string, v interface{}) *DeleteStmt {
+	stmt.where.AndLt(col, v)
+	return stmt
+}
+
+// OrLt 指定 where ... OR col<v 语句，具体说明可参考 WhereStmt.OrLt()
+func (stmt *DeleteStmt) OrLt(col string, v interface{}) *DeleteStmt {
+	stmt.where.OrLt(col, v)
+	return stmt
+}
+
+// AndLte 指定 where ... AND col<=v 语句，具体说明可参考 WhereStmt.AndLte()
+func (stmt *DeleteStmt) AndLte(col string, v interface{}) *DeleteStmt {
+	stmt.where.AndLte(col, v)
+	return stmt
+}
+
+// OrLte 指定 where ... OR col<=v 语句，具体说明可参考 WhereStmt.OrLte()
+func (stmt *DeleteStmt) OrLte(col string, v interface{}) *DeleteStmt {
+	stmt.where.OrLte(col, v)
+	return stmt
+}
+
 // Exec 执行 SQL 语句
 func (stmt *DeleteStmt) Exec() (sql.Result, error) {
 	return exec(stmt.engine, stmt)
@@ -15,6 +15,15 @@ type InsertStmt struct {
 	table  string
 	cols   []string
 	args   [][]interface{}
+
+	// INSERT ... SELECT 中的查询语句
+	sel *SelectStmt
+
+	// ON CONFLICT 相关的设置
+	dialect        Dialect
+	conflict       bool
+	conflictTarget []string
+	conflictUpdate []string
 }
 
 // Insert 声明一条插入语句
@@ -64,11 +73,42 @@ func (stmt *InsertStmt) Values(vals ...interface{}) *InsertStmt {
 	return stmt
 }
 
+// Select 指定以查询语句的结果作为插入的数据，即 INSERT INTO ... SELECT 语句。
+//
+// 插入的列由 Columns() 指定，不能再通过 Values() 和 KeyValue() 指定数据。
+// 若能确定 sel 返回的列数量，则会检测其是否与插入的列数量相同，
+// 不同时返回 ErrColumnsNotMatch。
+// sel 的内容在调用 SQL() 时才会生成。
+func (stmt *InsertStmt) Select(sel *SelectStmt) *InsertStmt {
+	stmt.sel = sel
+	return stmt
+}
+
+// OnConflict 指定在插入的数据与已有数据冲突时，改为更新已有数据。
+//
+// d 用于生成与数据库相关的冲突处理语句；
+// target 为用于检测冲突的列，mysql 会忽略此值，由其主键和唯一约束决定；
+// update 为发生冲突时需要更新的列，更新的值即为插入时指定的值，
+// 若未指定，则表示 target 之外的所有插入列。
+func (stmt *InsertStmt) OnConflict(d Dialect, target []string, update ...string) *InsertStmt {
+	stmt.dialect = d
+	stmt.conflict = true
+	stmt.conflictTarget = target
+	stmt.conflictUpdate = update
+	return stmt
+}
+
 // Reset 重置语句
 func (stmt *InsertStmt) Reset() {
 	stmt.table = ""
 	stmt.cols = stmt.cols[:0]
 	stmt.args = stmt.args[:0]
+	stmt.sel = nil
+
+	stmt.dialect = nil
+	stmt.conflict = false
+	stmt.conflictTarget = nil
+	stmt.conflictUpdate = nil
 }
 
 // SQL 获取 SQL 的语句及参数部分
@@ -81,6 +121,13 @@ func (stmt *InsertStmt) SQL() (string, []interface{}, error) {
 		return "", nil, ErrColumnsIsEmpty
 	}
 
+	if stmt.sel != nil {
+		if len(stmt.args) > 0 {
+			return "", nil, ErrSelectWithValues
+		}
+		return stmt.selectSQL()
+	}
+
 	if len(stmt.args) == 0 {
 		return "", nil, ErrValueIsEmpty
 	}
@@ -91,16 +138,7 @@ func (stmt *InsertStmt) SQL() (string, []interface{}, error) {
 		}
 	}
 
-	buffer := New("INSERT INTO ")
-	buffer.WriteString(stmt.table)
-
-	buffer.WriteByte('(')
-	for _, col := range stmt.cols {
-		buffer.WriteString(col)
-		buffer.WriteByte(',')
-	}
-	buffer.TruncateLast(1)
-	buffer.WriteByte(')')
+	buffer := stmt.header()
 
 	args := make([]interface{}, 0, len(stmt.cols)*len(stmt.args))
 	buffer.WriteString(" VALUES ")
@@ -121,9 +159,90 @@ func (stmt *InsertStmt) SQL() (string, []interface{}, error) {
 	}
 	buffer.TruncateLast(1)
 
+	if err := stmt.writeConflict(buffer); err != nil {
+		return "", nil, err
+	}
+
 	return buffer.String(), args, nil
 }
 
+// 生成 INSERT ... SELECT 语句
+func (stmt *InsertStmt) selectSQL() (string, []interface{}, error) {
+	if cnt := stmt.sel.columnCount(); cnt >= 0 && cnt != len(stmt.cols) {
+		return "", nil, ErrColumnsNotMatch
+	}
+
+	sel := stmt.sel
+	if stmt.conflict && sel.where.buffer.Len() == 0 && sel.cursor == nil {
+		// sqlite3 中 INSERT ... SELECT ... ON CONFLICT 存在歧义，
+		// 要求 SELECT 必须带 WHERE 子句，所以添加一个恒成立的条件。
+		// 复制一份 sel，不影响用户传入的语句。
+		s := *sel
+		s.where = newWhereStmt().And("1=1")
+		sel = &s
+	}
+
+	query, args, err := sel.SQL()
+	if err != nil {
+		return "", nil, err
+	}
+
+	buffer := stmt.header()
+	buffer.WriteByte(' ')
+	buffer.WriteString(query)
+
+	if err := stmt.writeConflict(buffer); err != nil {
+		return "", nil, err
+	}
+
+	return buffer.String(), args, nil
+}
+
+// 生成 INSERT INTO table(cols) 部分
+func (stmt *InsertStmt) header() *SQLBuilder {
+	buffer := New("INSERT INTO ")
+	buffer.WriteString(stmt.table)
+
+	buffer.WriteByte('(')
+	for _, col := range stmt.cols {
+		buffer.WriteString(col)
+		buffer.WriteByte(',')
+	}
+	buffer.TruncateLast(1)
+	buffer.WriteByte(')')
+
+	return buffer
+}
+
+func (stmt *InsertStmt) writeConflict(buffer *SQLBuilder) error {
+	if !stmt.conflict {
+		return nil
+	}
+
+	query, err := stmt.dialect.UpsertSQL(stmt.conflictTarget, stmt.upsertColumns())
+	if err != nil {
+		return err
+	}
+	buffer.WriteString(query)
+	return nil
+}
+
+// 获取在冲突时需要更新的列
+func (stmt *InsertStmt) upsertColumns() []string {
+	if len(stmt.conflictUpdate) > 0 {
+		return stmt.conflictUpdate
+	}
+
+	cols := make([]string, 0, len(stmt.cols))
+	for _, col := range stmt.cols {
+		if !inStrSlice(col, stmt.conflictTarget) {
+			cols = append(cols, col)
+		}
+	}
+
+	return cols
+}
+
 // Exec 执行 SQL 语句
 func (stmt *InsertStmt) Exec() (sql.Result, error) {
 	return exec(stmt.engine, stmt)
@@ -134,6 +253,61 @@ func (stmt *InsertStmt) ExecContext(ctx context.Context) (sql.Result, error) {
 	return execContext(ctx, stmt.engine, stmt)
 }
 
+// LastInsertID 执行 SQL 语句，并返回自增列 col 的值。
+//
+// d 用于确定获取自增列的方式，仅适用于插入单行数据的情况。
+func (stmt *InsertStmt) LastInsertID(d Dialect, col string) (int64, error) {
+	return stmt.LastInsertIDContext(context.Background(), d, col)
+}
+
+// LastInsertIDContext 执行 SQL 语句，并返回自增列 col 的值。
+//
+// d 用于确定获取自增列的方式，仅适用于插入单行数据的情况。
+func (stmt *InsertStmt) LastInsertIDContext(ctx context.Context, d Dialect, col string) (int64, error) {
+	query, args, err := stmt.SQL()
+	if err != nil {
+		return 0, err
+	}
+
+	idSQL, appendSQL := d.LastInsertIDSQL(stmt.table, col)
+	if idSQL == "" {
+		r, err := stmt.engine.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return r.LastInsertId()
+	}
+
+	if appendSQL {
+		query += idSQL
+	} else {
+		if _, err := stmt.engine.ExecContext(ctx, query, args...); err != nil {
+			return 0, err
+		}
+		query = idSQL
+		args = nil
+	}
+
+	rows, err := stmt.engine.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, sql.ErrNoRows
+	}
+
+	var id int64
+	if err = rows.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
 // Prepare 预编译
 func (stmt *InsertStmt) Prepare() (*sql.Stmt, error) {
 	return prepare(stmt.engine, stmt)
@@ -6,10 +6,12 @@ package sqlbuilder
 
 import (
 	"database/sql"
+	"strings"
 	"testing"
 
 	"github.com/issue9/assert"
 	"github.com/issue9/orm/internal/sqltest"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 var _ SQLer = &InsertStmt{}
@@ -77,3 +79,95 @@ func TestInsertError(t *testing.T) {
 	query, args, err = i.Columns("c1", "c2").Values(1).SQL()
 	a.Error(err).Nil(args).Empty(query)
 }
+
+func TestInsert_Select(t *testing.T) {
+	a := assert.New(t)
+
+	sel := Select(nil, nil).Select("id", "COALESCE(name,'a,b') AS name").From("users").Where("created<?", 100)
+	i := Insert(nil).Table("archive").Columns("id", "name").Select(sel)
+	query, args, err := i.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{100})
+	sqltest.Equal(a, query, "insert into archive (id,name) select id,COALESCE(name,'a,b') AS name from users where created<?")
+
+	// 列数量不匹配
+	i.Reset()
+	sel = Select(nil, nil).Select("id,name,age").From("users")
+	query, args, err = i.Table("archive").Columns("id", "name").Select(sel).SQL()
+	a.Equal(err, ErrColumnsNotMatch).Nil(args).Empty(query)
+
+	// 无法确定列数量时不检测
+	i.Reset()
+	sel = Select(nil, nil).Select("u.*").From("users AS u")
+	query, args, err = i.Table("archive").Columns("id", "name").Select(sel).SQL()
+	a.NotError(err).Empty(args)
+	sqltest.Equal(a, query, "insert into archive (id,name) select u.* from users as u")
+
+	// 不能同时指定 Values
+	i.Values(1, "n")
+	query, args, err = i.SQL()
+	a.Equal(err, ErrSelectWithValues).Nil(args).Empty(query)
+
+	// 查询语句错误
+	i.Reset()
+	query, args, err = i.Table("archive").Columns("id").Select(Select(nil, nil).Select("id")).SQL()
+	a.Equal(err, ErrTableIsEmpty).Nil(args).Empty(query)
+}
+
+// 仅实现了 UpsertSQL() 的 Dialect，生成与 sqlite3 相同的 ON CONFLICT 语句。
+type upsertDialect struct {
+	Dialect
+}
+
+func (d upsertDialect) UpsertSQL(target, update []string) (string, error) {
+	buf := New(" ON CONFLICT(").WriteString(strings.Join(target, ",")).WriteString(") DO UPDATE SET ")
+	for _, col := range update {
+		buf.WriteString(col).WriteString("=excluded.").WriteString(col).WriteByte(',')
+	}
+	buf.TruncateLast(1)
+
+	return buf.String(), nil
+}
+
+func TestInsert_SelectOnConflict(t *testing.T) {
+	a := assert.New(t)
+	db, err := sql.Open("sqlite3", "./test.db")
+	a.NotError(err)
+	defer func() {
+		_, err = db.Exec("DROP TABLE IF EXISTS test_src;DROP TABLE IF EXISTS test_dest")
+		a.NotError(err)
+		a.NotError(db.Close())
+	}()
+
+	_, err = db.Exec("DROP TABLE IF EXISTS test_src;DROP TABLE IF EXISTS test_dest;" +
+		"CREATE TABLE test_src(id INTEGER NOT NULL,name TEXT NOT NULL);" +
+		"CREATE TABLE test_dest(id INTEGER NOT NULL PRIMARY KEY,name TEXT NOT NULL);" +
+		"INSERT INTO test_src(id,name) VALUES(1,'s1'),(2,'s2');" +
+		"INSERT INTO test_dest(id,name) VALUES(1,'d1')")
+	a.NotError(err)
+
+	// SELECT 没有 WHERE 子句时，会自动添加一个恒成立的条件
+	sel := Select(db, nil).Select("id", "name").From("test_src")
+	stmt := Insert(db).Table("test_dest").
+		Columns("id", "name").
+		Select(sel).
+		OnConflict(upsertDialect{}, []string{"id"})
+	query, _, err := stmt.SQL()
+	a.NotError(err)
+	sqltest.Equal(a, query, "insert into test_dest (id,name) select id,name from test_src where 1=1 "+
+		"on conflict(id) do update set name=excluded.name")
+
+	_, err = stmt.Exec()
+	a.NotError(err)
+
+	// 传入的查询语句本身不受影响
+	query, _, err = sel.SQL()
+	a.NotError(err)
+	sqltest.Equal(a, query, "select id,name from test_src")
+
+	var cnt int
+	a.NotError(db.QueryRow("SELECT COUNT(*) FROM test_dest WHERE id=1 AND name='s1'").Scan(&cnt))
+	a.Equal(cnt, 1)
+	a.NotError(db.QueryRow("SELECT COUNT(*) FROM test_dest").Scan(&cnt))
+	a.Equal(cnt, 2)
+}
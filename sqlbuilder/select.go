@@ -7,9 +7,12 @@ package sqlbuilder
 import (
 	"context"
 	"database/sql"
+	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/issue9/orm/fetch"
+	"github.com/issue9/orm/internal/lexer"
 )
 
 // SelectStmt 查询语句
@@ -22,13 +25,26 @@ type SelectStmt struct {
 	distinct  bool
 	forupdate bool
 
+	// 列和表名中子查询对应的参数
+	colArgs   []interface{}
+	tableArgs []interface{}
+
+	// 生成子查询时的错误信息
+	err error
+
+	with withClause
+
 	// COUNT 查询的列内容
 	countExpr string
 
 	joins  []*join
-	orders *SQLBuilder
+	orders []*orderBy
 	group  string
 
+	// 键集分页的游标，cursorBefore 表示是否获取游标之前的记录
+	cursor       *Cursor
+	cursorBefore bool
+
 	havingQuery string
 	havingVals  []interface{}
 
@@ -42,6 +58,11 @@ type join struct {
 	table string
 }
 
+type orderBy struct {
+	cols []string
+	asc  bool
+}
+
 // Select 声明一条 Select 语句
 func Select(e Engine, d Dialect) *SelectStmt {
 	return &SelectStmt{
@@ -67,12 +88,20 @@ func (stmt *SelectStmt) Reset() {
 	stmt.distinct = false
 	stmt.forupdate = false
 
+	stmt.colArgs = nil
+	stmt.tableArgs = nil
+	stmt.err = nil
+	stmt.with.reset()
+
 	stmt.countExpr = ""
 
 	stmt.joins = stmt.joins[:0]
-	stmt.orders.Reset()
+	stmt.orders = stmt.orders[:0]
 	stmt.group = ""
 
+	stmt.cursor = nil
+	stmt.cursorBefore = false
+
 	stmt.havingQuery = ""
 	stmt.havingVals = nil
 
@@ -82,6 +111,10 @@ func (stmt *SelectStmt) Reset() {
 
 // SQL 获取 SQL 语句及对应的参数
 func (stmt *SelectStmt) SQL() (string, []interface{}, error) {
+	if stmt.err != nil {
+		return "", nil, stmt.err
+	}
+
 	if stmt.table == "" {
 		return "", nil, ErrTableIsEmpty
 	}
@@ -90,8 +123,13 @@ func (stmt *SelectStmt) SQL() (string, []interface{}, error) {
 		return "", nil, ErrColumnsIsEmpty
 	}
 
-	buf := New("SELECT ")
-	args := make([]interface{}, 0, 10)
+	withQuery, args, err := stmt.with.SQL()
+	if err != nil {
+		return "", nil, err
+	}
+
+	buf := New(withQuery)
+	buf.WriteString("SELECT ")
 
 	if stmt.countExpr == "" {
 		if stmt.distinct {
@@ -102,12 +140,14 @@ func (stmt *SelectStmt) SQL() (string, []interface{}, error) {
 			buf.WriteByte(',')
 		}
 		buf.TruncateLast(1)
+		args = append(args, stmt.colArgs...)
 	} else {
 		buf.WriteString(stmt.countExpr)
 	}
 
 	buf.WriteString(" FROM ")
 	buf.WriteString(stmt.table)
+	args = append(args, stmt.tableArgs...)
 
 	// join
 	if len(stmt.joins) > 0 {
@@ -124,7 +164,7 @@ func (stmt *SelectStmt) SQL() (string, []interface{}, error) {
 	}
 
 	// where
-	wq, wa, err := stmt.where.SQL()
+	wq, wa, err := stmt.whereSQL()
 	if err != nil {
 		return "", nil, err
 	}
@@ -146,8 +186,23 @@ func (stmt *SelectStmt) SQL() (string, []interface{}, error) {
 	}
 
 	// order by
-	if stmt.orders != nil && stmt.orders.Len() > 0 {
-		buf.WriteString(stmt.orders.String())
+	if len(stmt.orders) > 0 {
+		buf.WriteString(" ORDER BY ")
+		for _, order := range stmt.orders {
+			for _, c := range order.cols {
+				buf.WriteString(c)
+				buf.WriteByte(',')
+			}
+			buf.TruncateLast(1)
+
+			// 获取游标之前的记录时，需要反转排序方向
+			if order.asc != stmt.cursorBefore {
+				buf.WriteString(" ASC,")
+			} else {
+				buf.WriteString(" DESC,")
+			}
+		}
+		buf.TruncateLast(1)
 	}
 
 	// limit
@@ -174,10 +229,115 @@ func (stmt *SelectStmt) Select(cols ...string) *SelectStmt {
 	return stmt
 }
 
+// SelectQuery 以子查询 (sub) AS alias 作为返回的列
+//
+// sub 中的参数会按照列出现的位置合并到当前语句中。
+func (stmt *SelectStmt) SelectQuery(sub *SelectStmt, alias string) *SelectStmt {
+	query, args, err := subquery(sub)
+	if err != nil {
+		stmt.err = err
+		return stmt
+	}
+
+	stmt.cols = append(stmt.cols, query+" AS "+alias)
+	stmt.colArgs = append(stmt.colArgs, args...)
+	return stmt
+}
+
 // From 指定表名
 func (stmt *SelectStmt) From(table string) *SelectStmt {
 	stmt.table = table
+	stmt.tableArgs = nil
+
+	return stmt
+}
+
+// FromQuery 以子查询 (sub) AS alias 作为查询的表
+func (stmt *SelectStmt) FromQuery(sub *SelectStmt, alias string) *SelectStmt {
+	query, args, err := subquery(sub)
+	if err != nil {
+		stmt.err = err
+		return stmt
+	}
+
+	stmt.table = query + " AS " + alias
+	stmt.tableArgs = args
+	return stmt
+}
+
+// 返回查询结果的列数量，无法确定时(比如包含 * 的列)返回 -1。
+//
+// Select() 中的每一项都可能包含以逗号分隔的多个列，
+// 括号以及字符串中的逗号不作为列的分隔符。
+func (stmt *SelectStmt) columnCount() int {
+	cols := stmt.cols
+	if stmt.countExpr != "" {
+		cols = []string{stmt.countExpr}
+	}
+
+	cnt := 0
+	for _, col := range cols {
+		items := make([]string, 0, 2)
+		item := New("")
+		depth := 0
+
+		for _, token := range lexer.Split(col, false) {
+			if token.Kind != lexer.Code {
+				item.WriteString(token.Text)
+				continue
+			}
+
+			for i := 0; i < len(token.Text); i++ {
+				switch c := token.Text[i]; {
+				case c == '(':
+					depth++
+				case c == ')':
+					depth--
+				case c == ',' && depth == 0:
+					items = append(items, item.String())
+					item.Reset()
+					continue
+				}
+				item.WriteByte(token.Text[i])
+			}
+		}
+		items = append(items, item.String())
+
+		for _, c := range items {
+			c = strings.TrimSpace(c)
+			if c == "*" || strings.HasSuffix(c, ".*") {
+				return -1
+			}
+		}
+		cnt += len(items)
+	}
+	return cnt
+}
+
+// 生成 (SELECT ...) 形式的子查询语句
+//
+// 子查询的内容在调用时即已确定，之后再修改 sub 不会影响已经生成的语句。
+func subquery(sub *SelectStmt) (string, []interface{}, error) {
+	query, args, err := sub.SQL()
+	if err != nil {
+		return "", nil, err
+	}
+	return "(" + query + ")", args, nil
+}
+
+// With 在语句之前添加一条名为 name 的公用表表达式，即 WITH name(cols) AS (query)。
+//
+// query 一般为 *SelectStmt 或是 *CompoundStmt，cols 为可选的列名。
+func (stmt *SelectStmt) With(name string, query SQLer, cols ...string) *SelectStmt {
+	stmt.with.add(false, name, query, cols)
+	return stmt
+}
 
+// WithRecursive 添加一条递归的公用表表达式，生成的语句以 WITH RECURSIVE 开头。
+//
+// query 一般为以 UNION ALL 连接的 *CompoundStmt，其中的查询语句可以引用 name 本身。
+func (stmt *SelectStmt) WithRecursive(name string, query SQLer, cols ...string) *SelectStmt {
+	stmt.with.add(true, name, query, cols)
 	return stmt
 }
 
@@ -211,6 +371,174 @@ func (stmt *SelectStmt) Or(cond string, args ...interface{}) *SelectStmt {
 	return stmt
 }
 
+// AndIn 指定 where ... AND col IN(v...) 语句，具体说明可参考 WhereStmt.AndIn()
+func (stmt *SelectStmt) AndIn(col string, v ...interface{}) *SelectStmt {
+	stmt.where.AndIn(col, v...)
+	return stmt
+}
+
+// OrIn 指定 where ... OR col IN(v...) 语句，具体说明可参考 WhereStmt.OrIn()
+func (stmt *SelectStmt) OrIn(col string, v ...interface{}) *SelectStmt {
+	stmt.where.OrIn(col, v...)
+	return stmt
+}
+
+// AndNotIn 指定 where ... AND col NOT IN(v...) 语句，具体说明可参考 WhereStmt.AndNotIn()
+func (stmt *SelectStmt) AndNotIn(col string, v ...interface{}) *SelectStmt {
+	stmt.where.AndNotIn(col, v...)
+	return stmt
+}
+
+// OrNotIn 指定 where ... OR col NOT IN(v...) 语句，具体说明可参考 WhereStmt.OrNotIn()
+func (stmt *SelectStmt) OrNotIn(col string, v ...interface{}) *SelectStmt {
+	stmt.where.OrNotIn(col, v...)
+	return stmt
+}
+
+// AndExists 指定 where ... AND EXISTS(sub) 语句
+func (stmt *SelectStmt) AndExists(sub *SelectStmt) *SelectStmt {
+	stmt.where.AndExists(sub)
+	return stmt
+}
+
+// OrExists 指定 where ... OR EXISTS(sub) 语句
+func (stmt *SelectStmt) OrExists(sub *SelectStmt) *SelectStmt {
+	stmt.where.OrExists(sub)
+	return stmt
+}
+
+// AndNotExists 指定 where ... AND NOT EXISTS(sub) 语句
+func (stmt *SelectStmt) AndNotExists(sub *SelectStmt) *SelectStmt {
+	stmt.where.AndNotExists(sub)
+	return stmt
+}
+
+// OrNotExists 指定 where ... OR NOT EXISTS(sub) 语句
+func (stmt *SelectStmt) OrNotExists(sub *SelectStmt) *SelectStmt {
+	stmt.where.OrNotExists(sub)
+	return stmt
+}
+
+// AndBetween 指定 where ... AND col BETWEEN v1 AND v2 语句，具体说明可参考 WhereStmt.AndBetween()
+func (stmt *SelectStmt) AndBetween(col string, v1, v2 interface{}) *SelectStmt {
+	stmt.where.AndBetween(col, v1, v2)
+	return stmt
+}
+
+// OrBetween 指定 where ... OR col BETWEEN v1 AND v2 语句，具体说明可参考 WhereStmt.OrBetween()
+func (stmt *SelectStmt) OrBetween(col string, v1, v2 interface{}) *SelectStmt {
+	stmt.where.OrBetween(col, v1, v2)
+	return stmt
+}
+
+// AndLike 指定 where ... AND col LIKE pattern 语句，具体说明可参考 WhereStmt.AndLike()
+func (stmt *SelectStmt) AndLike(col string, pattern string) *SelectStmt {
+	stmt.where.AndLike(col, pattern)
+	return stmt
+}
+
+// OrLike 指定 where ... OR col LIKE pattern 语句，具体说明可参考 WhereStmt.OrLike()
+func (stmt *SelectStmt) OrLike(col string, pattern string) *SelectStmt {
+	stmt.where.OrLike(col, pattern)
+	return stmt
+}
+
+// AndIsNull 指定 where ... AND col IS NULL 语句，具体说明可参考 WhereStmt.AndIsNull()
+func (stmt *SelectStmt) AndIsNull(col string) *SelectStmt {
+	stmt.where.AndIsNull(col)
+	return stmt
+}
+
+// OrIsNull 指定 where ... OR col IS NULL 语句，具体说明可参考 WhereStmt.OrIsNull()
+func (stmt *SelectStmt) OrIsNull(col string) *SelectStmt {
+	stmt.where.OrIsNull(col)
+	return stmt
+}
+
+// AndNotNull 指定 where ... AND col IS NOT NULL 语句，具体说明可参考 WhereStmt.AndNotNull()
+func (stmt *SelectStmt) AndNotNull(col string) *SelectStmt {
+	stmt.where.AndNotNull(col)
+	return stmt
+}
+
+// OrNotNull 指定 where ... OR col IS NOT NULL 语句，具体说明可参考 WhereStmt.OrNotNull()
+func (stmt *SelectStmt) OrNotNull(col string) *SelectStmt {
+	stmt.where.OrNotNull(col)
+	return stmt
+}
+
+// AndEq 指定 where ... AND col=v 语句，具体说明可参考 WhereStmt.AndEq()
+func (stmt *SelectStmt) AndEq(col string, v interface{}) *SelectStmt {
+	stmt.where.AndEq(col, v)
+	return stmt
+}
+
+// OrEq 指定 where ... OR col=v 语句，具体说明可参考 WhereStmt.OrEq()
+func (stmt *SelectStmt) OrEq(col string, v interface{}) *SelectStmt {
+	stmt.where.OrEq(col, v)
+	return stmt
+}
+
+// AndNeq 指定 where ... AND col<>v 语句，具体说明可参考 WhereStmt.AndNeq()
+func (stmt *SelectStmt) AndNeq(col string, v interface{}) *SelectStmt {
+	stmt.where.AndNeq(col, v)
+	return stmt
+}
+
+// OrNeq 指定 where ... OR col<>v 语句，具体说明可参考 WhereStmt.OrNeq()
+func (stmt *SelectStmt) OrNeq(col string, v interface{}) *SelectStmt {
+	stmt.where.OrNeq(col, v)
+	return stmt
+}
+
+// AndGt 指定 where ... AND col>v 语句，具体说明可参考 WhereStmt.AndGt()
+func (stmt *SelectStmt) AndGt(col string, v interface{}) *SelectStmt {
+	stmt.where.AndGt(col, v)
+	return stmt
+}
+
+// OrGt 指定 where ... OR col>v 语句，具体说明可参考 WhereStmt.OrGt()
+func (stmt *SelectStmt) OrGt(col string, v interface{}) *SelectStmt {
+	stmt.where.OrGt(col, v)
+	return stmt
+}
+
+// AndGte 指定 where ... AND col>=v 语句，具体说明可参考 WhereStmt.AndGte()
+func (stmt *SelectStmt) AndGte(col string, v interface{}) *SelectStmt {
+	stmt.where.AndGte(col, v)
+	return stmt
+}
+
+// OrGte 指定 where ... OR col>=v 语句，具体说明可参考 WhereStmt.OrGte()
+func (stmt *SelectStmt) OrGte(col string, v interface{}) *SelectStmt {
+	stmt.where.OrGte(col, v)
+	return stmt
+}
+
+// AndLt 指定 where ... AND col<v 语句，具体说明可参考 WhereStmt.AndLt()
+func (stmt *SelectStmt) AndLt(col string, v interface{}) *SelectStmt {
+	stmt.where.AndLt(col, v)
+	return stmt
+}
+
+// OrLt 指定 where ... OR col<v 语句，具体说明可参考 WhereStmt.OrLt()
+func (stmt *SelectStmt) OrLt(col string, v interface{}) *SelectStmt {
+	stmt.where.OrLt(col, v)
+	return stmt
+}
+
+// AndLte 指定 where ... AND col<=v 语句，具体说明可参考 WhereStmt.AndLte()
+func (stmt *SelectStmt) AndLte(col string, v interface{}) *SelectStmt {
+	stmt.where.AndLte(col, v)
+	return stmt
+}
+
+// OrLte 指定 where ... OR col<=v 语句，具体说明可参考 WhereStmt.OrLte()
+func (stmt *SelectStmt) OrLte(col string, v interface{}) *SelectStmt {
+	stmt.where.OrLte(col, v)
+	return stmt
+}
+
 // Join 添加一条 Join 语句
 func (stmt *SelectStmt) Join(typ, table, on string) *SelectStmt {
 	if stmt.joins == nil {
@@ -232,29 +560,63 @@ func (stmt *SelectStmt) Asc(col ...string) *SelectStmt {
 }
 
 func (stmt *SelectStmt) orderBy(asc bool, col ...string) *SelectStmt {
-	if stmt.orders == nil {
-		stmt.orders = New("")
+	stmt.orders = append(stmt.orders, &orderBy{cols: col, asc: asc})
+	return stmt
+}
+
+// After 仅获取位于游标 c 之后的记录，用于键集分页。
+//
+// 记录的先后顺序由 Asc() 和 Desc() 指定的排序列决定，
+// 排序列的值不能为 NULL，且组合起来应该是唯一的，比如以主键作为最后一个排序列。
+// 传递 nil 表示取消游标。
+func (stmt *SelectStmt) After(c *Cursor) *SelectStmt {
+	stmt.cursor = c
+	stmt.cursorBefore = false
+	return stmt
+}
+
+// Before 仅获取位于游标 c 之前的记录，用于键集分页。
+//
+// 为了获取紧邻游标的记录，生成的 SQL 会反转排序方向，
+// 通过 QueryObj() 查询时会再次反转，使结果依然按 Asc() 和 Desc() 指定的顺序排列。
+// 其它规则与 After() 相同，传递 nil 表示取消游标，排序方向也会恢复。
+func (stmt *SelectStmt) Before(c *Cursor) *SelectStmt {
+	stmt.cursor = c
+	stmt.cursorBefore = c != nil
+	return stmt
+}
+
+// 生成 where 部分的语句，包含游标的查询条件。
+func (stmt *SelectStmt) whereSQL() (string, []interface{}, error) {
+	if stmt.cursor == nil {
+		return stmt.where.SQL()
 	}
 
-	if stmt.orders.Len() == 0 {
-		stmt.orders.WriteString(" ORDER BY ")
-	} else {
-		stmt.orders.WriteByte(',')
+	cols, asc := stmt.orderColumns()
+	for i := range asc {
+		asc[i] = asc[i] != stmt.cursorBefore
 	}
 
-	for _, c := range col {
-		stmt.orders.WriteString(c)
-		stmt.orders.WriteByte(',')
+	cond, args, err := cursorCond(stmt.dialect, cols, asc, stmt.cursor.values)
+	if err != nil {
+		return "", nil, err
 	}
-	stmt.orders.TruncateLast(1)
 
-	if asc {
-		stmt.orders.WriteString(" ASC ")
-	} else {
-		stmt.orders.WriteString(" DESC ")
+	w := newWhereStmt()
+	w.AndWhere(stmt.where).And(cond, args...)
+	return w.SQL()
+}
+
+// 获取所有的排序列及其对应的排序方向
+func (stmt *SelectStmt) orderColumns() (cols []string, asc []bool) {
+	for _, order := range stmt.orders {
+		for _, c := range order.cols {
+			cols = append(cols, c)
+			asc = append(asc, order.asc)
+		}
 	}
 
-	return stmt
+	return cols, asc
 }
 
 // ForUpdate 添加 FOR UPDATE 语句部分
@@ -308,14 +670,63 @@ func (stmt *SelectStmt) QueryContext(ctx context.Context) (*sql.Rows, error) {
 // QueryObj 将符合当前条件的所有记录依次写入 objs 中。
 //
 // 关于 objs 的值类型，可以参考 github.com/issue9/orm/fetch.Obj 函数的相关介绍。
-func (stmt *SelectStmt) QueryObj(objs interface{}) (int, error) {
-	rows, err := stmt.Query()
+//
+// 同时返回第一条和最后一条记录对应的游标，用于键集分页：
+// prev 可以传递给 Before() 获取上一页的数据，next 可以传递给 After() 获取下一页的数据。
+// 没有记录、未指定排序列或是排序列未出现在查询结果中时，两者都为 nil。
+func (stmt *SelectStmt) QueryObj(objs interface{}) (cnt int, prev, next *Cursor, err error) {
+	return stmt.QueryObjContext(context.Background(), objs)
+}
+
+// QueryObjContext 将符合当前条件的所有记录依次写入 objs 中，
+// 同时返回第一条和最后一条记录对应的游标。
+//
+// 关于 objs 的值类型，可以参考 github.com/issue9/orm/fetch.Obj 函数的相关介绍。
+func (stmt *SelectStmt) QueryObjContext(ctx context.Context, objs interface{}) (cnt int, prev, next *Cursor, err error) {
+	rval := reflect.ValueOf(objs)
+	once := rval.Kind() == reflect.Ptr && rval.Elem().Kind() == reflect.Struct // 与 fetch.Obj 相同，结构指针只导出一条
+
+	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
-		return 0, err
+		return 0, nil, nil, err
 	}
 	defer rows.Close()
 
-	return fetch.Obj(objs, rows)
+	mapped, err := fetch.Map(once, rows)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	if stmt.cursor != nil && stmt.cursorBefore { // 恢复原来的排序
+		for i, j := 0, len(mapped)-1; i < j; i, j = i+1, j-1 {
+			mapped[i], mapped[j] = mapped[j], mapped[i]
+		}
+	}
+
+	if cnt, err = fetch.ObjFromMap(objs, mapped); err != nil || cnt == 0 {
+		return cnt, nil, nil, err
+	}
+
+	cols, _ := stmt.orderColumns()
+	if len(cols) == 0 {
+		return cnt, nil, nil, nil
+	}
+
+	prev = newCursor(cols, objAt(rval, 0), mapped[0])
+	next = newCursor(cols, objAt(rval, cnt-1), mapped[cnt-1])
+	return cnt, prev, next, nil
+}
+
+// 获取 objs 中第 i 个导出的对象，objs 的类型与 fetch.Obj 的参数相同。
+func objAt(objs reflect.Value, i int) reflect.Value {
+	for objs.Kind() == reflect.Ptr {
+		objs = objs.Elem()
+	}
+
+	if objs.Kind() == reflect.Struct {
+		return objs
+	}
+	return objs.Index(i)
 }
 
 // QueryInt 查询指定列的第一行数据，并将其转换成 int
@@ -333,3 +744,19 @@ func (stmt *SelectStmt) QueryInt(colName string) (int64, error) {
 
 	return strconv.ParseInt(cols[0], 10, 64)
 }
+
+// QueryIntContext 查询指定列的第一行数据，并将其转换成 int
+func (stmt *SelectStmt) QueryIntContext(ctx context.Context, colName string) (int64, error) {
+	rows, err := stmt.QueryContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	cols, err := fetch.ColumnString(true, colName, rows)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(cols[0], 10, 64)
+}
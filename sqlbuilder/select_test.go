@@ -70,4 +70,110 @@ func TestSelect_sqlite3(t *testing.T) {
 	query, args, err = s.SQL()
 	a.NotError(err).Empty(args)
 	sqltest.Equal(a, query, "select c1,c2 from #tb1")
+	// 条件辅助函数
+	s.Reset()
+	s.Select("c1").From("tb1").AndIn("id", []int{1, 2}).AndNotNull("c1").OrLike("c2", "a%")
+	query, args, err = s.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{1, 2, "a%"})
+	sqltest.Equal(a, query, "select c1 from tb1 where id IN(?,?) AND c1 IS NOT NULL OR c2 LIKE ? ESCAPE '!'")
+}
+
+func TestSelect_cursor(t *testing.T) {
+	a := assert.New(t)
+
+	// 单列
+	s := sqlbuilder.Select(nil, dialect.Sqlite3()).Select("*").
+		From("table").
+		Where("c1=?", 1).
+		Desc("id").
+		After(sqlbuilder.NewCursor(5))
+	query, args, err := s.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{1, 5})
+	sqltest.Equal(a, query, "select * from table where (c1=?) and id<? order by id desc")
+
+	// 多列，方向相同，使用行值比较
+	s = sqlbuilder.Select(nil, dialect.Sqlite3()).Select("*").
+		From("table").
+		Asc("created", "id").
+		After(sqlbuilder.NewCursor(10, 5))
+	query, args, err = s.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{10, 5})
+	sqltest.Equal(a, query, "select * from table where (created,id)>(?,?) order by created,id asc")
+
+	// Before，反转排序方向
+	s.Before(sqlbuilder.NewCursor(10, 5))
+	query, args, err = s.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{10, 5})
+	sqltest.Equal(a, query, "select * from table where (created,id)<(?,?) order by created,id desc")
+
+	// Before(nil) 取消游标，不再反转排序方向
+	s.Before(nil)
+	query, args, err = s.SQL()
+	a.NotError(err).Empty(args)
+	sqltest.Equal(a, query, "select * from table order by created,id asc")
+
+	// mysql 展开成 OR 语句
+	s = sqlbuilder.Select(nil, dialect.Mysql()).Select("*").
+		From("table").
+		Asc("created", "id").
+		After(sqlbuilder.NewCursor(10, 5))
+	query, args, err = s.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{10, 10, 5})
+	sqltest.Equal(a, query, "select * from table where ((created>?) or (created=? and id>?)) order by created,id asc")
+
+	// 方向不同，展开成 OR 语句
+	s = sqlbuilder.Select(nil, dialect.Postgres()).Select("*").
+		From("table").
+		Desc("created").
+		Asc("id").
+		Before(sqlbuilder.NewCursor(10, 5))
+	query, args, err = s.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{10, 10, 5})
+	sqltest.Equal(a, query, "select * from table where ((created>?) or (created=? and id<?)) order by created asc,id desc")
+
+	// 游标与排序列的数量不匹配
+	s.After(sqlbuilder.NewCursor(10))
+	query, args, err = s.SQL()
+	a.Equal(err, sqlbuilder.ErrCursorNotMatch).Empty(query).Nil(args)
+}
+
+func TestSelect_subquery(t *testing.T) {
+	a := assert.New(t)
+	d := dialect.Sqlite3()
+
+	cnt := sqlbuilder.Select(nil, d).Count("COUNT(*)").From("posts AS p").Where("p.uid=u.id AND p.state=?", 1)
+	active := sqlbuilder.Select(nil, d).Select("id", "name").From("users").Where("active=?", true)
+	banned := sqlbuilder.Select(nil, d).Select("uid").From("bans").Where("expired>?", 100)
+	exists := sqlbuilder.Select(nil, d).Select("1").From("emails AS e").Where("e.uid=u.id AND e.verified=?", 2)
+
+	s := sqlbuilder.Select(nil, d).Select("u.id").
+		SelectQuery(cnt, "posts").
+		FromQuery(active, "u").
+		Where("u.name LIKE ?", "a%").
+		AndNotIn("u.id", banned).
+		AndExists(exists).
+		Limit(10)
+	query, args, err := s.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{1, true, "a%", 100, 2, 10})
+	sqltest.Equal(a, query, "select u.id,(select count(*) from posts as p where p.uid=u.id and p.state=?) as posts "+
+		"from (select id,name from users where active=?) as u "+
+		"where u.name like ? and u.id not in(select uid from bans where expired>?) "+
+		"and exists(select 1 from emails as e where e.uid=u.id and e.verified=?) limit ?")
+
+	// 子查询错误
+	s = sqlbuilder.Select(nil, d).Select("*").FromQuery(sqlbuilder.Select(nil, d).From("users"), "u")
+	query, args, err = s.SQL()
+	a.Equal(err, sqlbuilder.ErrColumnsIsEmpty).Empty(query).Nil(args)
+
+	// Reset 清除子查询的参数和错误
+	s.Reset()
+	query, args, err = s.SQL()
+	a.Equal(err, sqlbuilder.ErrTableIsEmpty)
 }
@@ -28,6 +28,15 @@ var (
 
 	// ErrArgsNotMatch 在生成的 SQL 语句中，传递的参数与语句的占位符数量不匹配。
 	ErrArgsNotMatch = errors.New("列与值的数量不匹配")
+
+	// ErrCursorNotMatch 在 Select 语句中，游标中值的数量与排序列的数量不匹配。
+	ErrCursorNotMatch = errors.New("游标与排序列的数量不匹配")
+
+	// ErrColumnsNotMatch 在 Insert 语句中，插入的列与 Select 返回的列数量不匹配。
+	ErrColumnsNotMatch = errors.New("插入的列与查询的列数量不匹配")
+
+	// ErrSelectWithValues 在 Insert 语句中，同时指定了 Values 和 Select。
+	ErrSelectWithValues = errors.New("不能同时指定 Values 和 Select")
 )
 
 // SQLBuilder 对 bytes.Buffer 的一个简单封装。
@@ -88,3 +97,12 @@ func (b *SQLBuilder) Bytes() []byte {
 func (b *SQLBuilder) Len() int {
 	return b.buffer().Len()
 }
+
+func inStrSlice(key string, slice []string) bool {
+	for _, v := range slice {
+		if v == key {
+			return true
+		}
+	}
+	return false
+}
@@ -34,4 +34,11 @@ func TestTruncate_sqlite3(t *testing.T) {
 	query, args, err = sql.SQL()
 	a.NotError(err).Empty(args)
 	sqltest.Equal(a, query, "delete from #tb2;delete from SQLITE_SEQUENCE WHERE name='#tb2';")
+
+	// 字符串中的表名不包含 {}
+	sql.Reset()
+	sql.Table("{test_tb1}").AI("{c1}")
+	query, args, err = sql.SQL()
+	a.NotError(err).Empty(args)
+	sqltest.Equal(a, query, "delete from {test_tb1};delete from SQLITE_SEQUENCE WHERE name='test_tb1';")
 }
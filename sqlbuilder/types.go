@@ -32,6 +32,7 @@ type Engine interface {
 	//  select * from #user where {group}=1
 	//  // 转换后
 	//  select * from prefix_user where `group`=1
+	// 字符串和注释中的内容不会被替换，## 表示 # 字符本身。
 	Query(query string, args ...interface{}) (*sql.Rows, error)
 
 	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
@@ -50,10 +51,13 @@ type Dialect interface {
 	// 返回符合当前数据库规范的引号对。
 	QuoteTuple() (openQuote, closeQuote byte)
 
-	// 根据当前的数据库，对 SQL 作调整。
+	// 根据当前的数据库，对 SQL 及其参数作调整。
 	//
 	// 比如占位符 postgresql 可以使用 $1 等形式。
-	SQL(sql string) (string, error)
+	// 语句中以 @name 表示的命名参数，会被转换成当前数据库的占位符，
+	// 同时按占位符的顺序调整 args 中对应的 sql.NamedArg 值。
+	// args 为 nil 时(比如预编译语句)，仅对语句本身作调整。
+	SQL(query string, args []interface{}) (string, []interface{}, error)
 
 	// 生成 `LIMIT N OFFSET M` 或是相同的语意的语句。
 	//
@@ -64,8 +68,29 @@ type Dialect interface {
 	LimitSQL(limit interface{}, offset ...interface{}) (string, []interface{})
 
 	// 清空表内容，重置 AI。
+	//
+	// 部分数据库(比如 sqlite3)在重置 AI 计数时，表名会出现在字符串中，
+	// 而字符串中的 # 并不会被替换成表名前缀，所以 table 应该是包含前缀的完整表名，
+	// 可以用 {} 包含，出现在字符串中时会被去掉。
 	TruncateTableSQL(table, aiColumn string) string
 
+	// 生成 INSERT 语句中处理冲突的部分，比如 ON CONFLICT 或是 ON DUPLICATE KEY UPDATE。
+	//
+	// target 为用于检测冲突的列，部分数据库可能会忽略此值；
+	// update 为发生冲突时需要更新的列，其值为插入时指定的值。
+	UpsertSQL(target, update []string) (string, error)
+
+	// 获取自增列的值的 SQL 语句。
+	//
+	// 若 sql 为空，表示通过 sql.Result.LastInsertId() 获取；
+	// 若 append 为 true，表示 sql 需要追加在 INSERT 语句之后，
+	// 比如 postgresql 的 RETURNING 子句，否则表示在插入之后单独执行 sql 语句获取。
+	LastInsertIDSQL(table, col string) (sql string, append bool)
+
+	// 是否采用行值比较的形式生成多列的比较语句，比如 (a,b) > (1,2)，
+	// 否则会展开成 a>1 OR (a=1 AND b>2) 的形式。
+	RowValueComparison() bool
+
 	// 是否允许在事务中执行 DDL
 	//
 	// 比如在 postgresql 中，如果创建一个带索引的表，会采用在事务中，
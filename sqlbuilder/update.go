@@ -19,6 +19,8 @@ type UpdateStmt struct {
 
 	occColumn string      // 乐观锁的列名
 	occValue  interface{} // 乐观锁的当前值
+
+	with withClause
 }
 
 // 表示一条 SET 语句。比如 set key=val
@@ -83,6 +85,23 @@ func (stmt *UpdateStmt) OCC(col string, val interface{}) *UpdateStmt {
 	return stmt
 }
 
+// With 在语句之前添加一条名为 name 的公用表表达式，即 WITH name(cols) AS (query)。
+//
+// query 一般为 *SelectStmt 或是 *CompoundStmt，cols 为可选的列名。
+// 可以在 Where() 等条件中引用 name，比如 {id} IN(SELECT {id} FROM name)。
+func (stmt *UpdateStmt) With(name string, query SQLer, cols ...string) *UpdateStmt {
+	stmt.with.add(false, name, query, cols)
+	return stmt
+}
+
+// WithRecursive 添加一条递归的公用表表达式，生成的语句以 WITH RECURSIVE 开头。
+//
+// query 一般为以 UNION ALL 连接的 *CompoundStmt，其中的查询语句可以引用 name 本身。
+func (stmt *UpdateStmt) WithRecursive(name string, query SQLer, cols ...string) *UpdateStmt {
+	stmt.with.add(true, name, query, cols)
+	return stmt
+}
+
 // WhereStmt 实现 WhereStmter 接口
 func (stmt *UpdateStmt) WhereStmt() *WhereStmt {
 	return stmt.where
@@ -105,6 +124,174 @@ func (stmt *UpdateStmt) Or(cond string, args ...interface{}) *UpdateStmt {
 	return stmt
 }
 
+// AndIn 指定 where ... AND col IN(v...) 语句，具体说明可参考 WhereStmt.AndIn()
+func (stmt *UpdateStmt) AndIn(col string, v ...interface{}) *UpdateStmt {
+	stmt.where.AndIn(col, v...)
+	return stmt
+}
+
+// OrIn 指定 where ... OR col IN(v...) 语句，具体说明可参考 WhereStmt.OrIn()
+func (stmt *UpdateStmt) OrIn(col string, v ...interface{}) *UpdateStmt {
+	stmt.where.OrIn(col, v...)
+	return stmt
+}
+
+// AndNotIn 指定 where ... AND col NOT IN(v...) 语句，具体说明可参考 WhereStmt.AndNotIn()
+func (stmt *UpdateStmt) AndNotIn(col string, v ...interface{}) *UpdateStmt {
+	stmt.where.AndNotIn(col, v...)
+	return stmt
+}
+
+// OrNotIn 指定 where ... OR col NOT IN(v...) 语句，具体说明可参考 WhereStmt.OrNotIn()
+func (stmt *UpdateStmt) OrNotIn(col string, v ...interface{}) *UpdateStmt {
+	stmt.where.OrNotIn(col, v...)
+	return stmt
+}
+
+// AndExists 指定 where ... AND EXISTS(sub) 语句
+func (stmt *UpdateStmt) AndExists(sub *SelectStmt) *UpdateStmt {
+	stmt.where.AndExists(sub)
+	return stmt
+}
+
+// OrExists 指定 where ... OR EXISTS(sub) 语句
+func (stmt *UpdateStmt) OrExists(sub *SelectStmt) *UpdateStmt {
+	stmt.where.OrExists(sub)
+	return stmt
+}
+
+// AndNotExists 指定 where ... AND NOT EXISTS(sub) 语句
+func (stmt *UpdateStmt) AndNotExists(sub *SelectStmt) *UpdateStmt {
+	stmt.where.AndNotExists(sub)
+	return stmt
+}
+
+// OrNotExists 指定 where ... OR NOT EXISTS(sub) 语句
+func (stmt *UpdateStmt) OrNotExists(sub *SelectStmt) *UpdateStmt {
+	stmt.where.OrNotExists(sub)
+	return stmt
+}
+
+// AndBetween 指定 where ... AND col BETWEEN v1 AND v2 语句，具体说明可参考 WhereStmt.AndBetween()
+func (stmt *UpdateStmt) AndBetween(col string, v1, v2 interface{}) *UpdateStmt {
+	stmt.where.AndBetween(col, v1, v2)
+	return stmt
+}
+
+// OrBetween 指定 where ... OR col BETWEEN v1 AND v2 语句，具体说明可参考 WhereStmt.OrBetween()
+func (stmt *UpdateStmt) OrBetween(col string, v1, v2 interface{}) *UpdateStmt {
+	stmt.where.OrBetween(col, v1, v2)
+	return stmt
+}
+
+// AndLike 指定 where ... AND col LIKE pattern 语句，具体说明可参考 WhereStmt.AndLike()
+func (stmt *UpdateStmt) AndLike(col string, pattern string) *UpdateStmt {
+	stmt.where.AndLike(col, pattern)
+	return stmt
+}
+
+// OrLike 指定 where ... OR col LIKE pattern 语句，具体说明可参考 WhereStmt.OrLike()
+func (stmt *UpdateStmt) OrLike(col string, pattern string) *UpdateStmt {
+	stmt.where.OrLike(col, pattern)
+	return stmt
+}
+
+// AndIsNull 指定 where ... AND col IS NULL 语句，具体说明可参考 WhereStmt.AndIsNull()
+func (stmt *UpdateStmt) AndIsNull(col string) *UpdateStmt {
+	stmt.where.AndIsNull(col)
+	return stmt
+}
+
+// OrIsNull 指定 where ... OR col IS NULL 语句，具体说明可参考 WhereStmt.OrIsNull()
+func (stmt *UpdateStmt) OrIsNull(col string) *UpdateStmt {
+	stmt.where.OrIsNull(col)
+	return stmt
+}
+
+// AndNotNull 指定 where ... AND col IS NOT NULL 语句，具体说明可参考 WhereStmt.AndNotNull()
+func (stmt *UpdateStmt) AndNotNull(col string) *UpdateStmt {
+	stmt.where.AndNotNull(col)
+	return stmt
+}
+
+// OrNotNull 指定 where ... OR col IS NOT NULL 语句，具体说明可参考 WhereStmt.OrNotNull()
+func (stmt *UpdateStmt) OrNotNull(col string) *UpdateStmt {
+	stmt.where.OrNotNull(col)
+	return stmt
+}
+
+// AndEq 指定 where ... AND col=v 语句，具体说明可参考 WhereStmt.AndEq()
+func (stmt *UpdateStmt) AndEq(col string, v interface{}) *UpdateStmt {
+	stmt.where.AndEq(col, v)
+	return stmt
+}
+
+// OrEq 指定 where ... OR col=v 语句，具体说明可参考 WhereStmt.OrEq()
+func (stmt *UpdateStmt) OrEq(col string, v interface{}) *UpdateStmt {
+	stmt.where.OrEq(col, v)
+	return stmt
+}
+
+// AndNeq 指定 where ... AND col<>v 语句，具体说明可参考 WhereStmt.AndNeq()
+func (stmt *UpdateStmt) AndNeq(col string, v interface{}) *UpdateStmt {
+	stmt.where.AndNeq(col, v)
+	return stmt
+}
+
+// OrNeq 指定 where ... OR col<>v 语句，具体说明可参考 WhereStmt.OrNeq()
+func (stmt *UpdateStmt) OrNeq(col string, v interface{}) *UpdateStmt {
+	stmt.where.OrNeq(col, v)
+	return stmt
+}
+
+// AndGt 指定 where ... AND col>v 语句，具体说明可参考 WhereStmt.AndGt()
+func (stmt *UpdateStmt) AndGt(col string, v interface{}) *UpdateStmt {
+	stmt.where.AndGt(col, v)
+	return stmt
+}
+
+// OrGt 指定 where ... OR col>v 语句，具体说明可参考 WhereStmt.OrGt()
+func (stmt *UpdateStmt) OrGt(col string, v interface{}) *UpdateStmt {
+	stmt.where.OrGt(col, v)
+	return stmt
+}
+
+// AndGte 指定 where ... AND col>=v 语句，具体说明可参考 WhereStmt.AndGte()
+func (stmt *UpdateStmt) AndGte(col string, v interface{}) *UpdateStmt {
+	stmt.where.AndGte(col, v)
+	return stmt
+}
+
+// OrGte 指定 where ... OR col>=v 语句，具体说明可参考 WhereStmt.OrGte()
+func (stmt *UpdateStmt) OrGte(col string, v interface{}) *UpdateStmt {
+	stmt.where.OrGte(col, v)
+	return stmt
+}
+
+// AndLt 指定 where ... AND col<v 语句，具体说明可参考 WhereStmt.AndLt()
+func (stmt *UpdateStmt) AndLt(col string, v interface{}) *UpdateStmt {
+	stmt.where.AndLt(col, v)
+	return stmt
+}
+
+// OrLt 指定 where ... OR col<v 语句，具体说明可参考 WhereStmt.OrLt()
+func (stmt *UpdateStmt) OrLt(col string, v interface{}) *UpdateStmt {
+	stmt.where.OrLt(col, v)
+	return stmt
+}
+
+// AndLte 指定 where ... AND col<=v 语句，具体说明可参考 WhereStmt.AndLte()
+func (stmt *UpdateStmt) AndLte(col string, v interface{}) *UpdateStmt {
+	stmt.where.AndLte(col, v)
+	return stmt
+}
+
+// OrLte 指定 where ... OR col<=v 语句，具体说明可参考 WhereStmt.OrLte()
+func (stmt *UpdateStmt) OrLte(col string, v interface{}) *UpdateStmt {
+	stmt.where.OrLte(col, v)
+	return stmt
+}
+
 // Reset 重置语句
 func (stmt *UpdateStmt) Reset() {
 	stmt.table = ""
@@ -113,6 +300,8 @@ func (stmt *UpdateStmt) Reset() {
 
 	stmt.occColumn = ""
 	stmt.occValue = nil
+
+	stmt.with.reset()
 }
 
 // SQL 获取 SQL 语句以及对应的参数
@@ -121,12 +310,16 @@ func (stmt *UpdateStmt) SQL() (string, []interface{}, error) {
 		return "", nil, err
 	}
 
-	buf := New("UPDATE ")
+	withQuery, args, err := stmt.with.SQL()
+	if err != nil {
+		return "", nil, err
+	}
+
+	buf := New(withQuery)
+	buf.WriteString("UPDATE ")
 	buf.WriteString(stmt.table)
 	buf.WriteString(" SET ")
 
-	args := make([]interface{}, 0, len(stmt.values))
-
 	for _, val := range stmt.values {
 		buf.WriteString(val.column)
 		buf.WriteByte('=')
@@ -5,6 +5,7 @@
 package sqlbuilder
 
 import (
+	"reflect"
 	"strings"
 )
 
@@ -12,6 +13,9 @@ import (
 type WhereStmt struct {
 	buffer *SQLBuilder
 	args   []interface{}
+
+	// 生成子查询时的错误信息，在 SQL() 中返回
+	err error
 }
 
 func newWhereStmt() *WhereStmt {
@@ -25,10 +29,15 @@ func newWhereStmt() *WhereStmt {
 func (stmt *WhereStmt) Reset() {
 	stmt.buffer.Reset()
 	stmt.args = stmt.args[:0]
+	stmt.err = nil
 }
 
 // SQL 生成 SQL 语句和对应的参数返回
 func (stmt *WhereStmt) SQL() (string, []interface{}, error) {
+	if stmt.err != nil {
+		return "", nil, stmt.err
+	}
+
 	cnt := 0
 	for _, c := range stmt.buffer.Bytes() {
 		if c == '?' || c == '@' {
@@ -77,6 +86,11 @@ func (stmt *WhereStmt) Or(cond string, args ...interface{}) *WhereStmt {
 }
 
 func (stmt *WhereStmt) addWhere(and bool, w *WhereStmt) *WhereStmt {
+	if w.err != nil { // 子条件中的子查询出错
+		stmt.err = w.err
+		return stmt
+	}
+
 	cond := w.buffer.String()
 	if strings.TrimSpace(cond) == "" {
 		return stmt
@@ -102,3 +116,227 @@ func (stmt *WhereStmt) AndWhere(w *WhereStmt) *WhereStmt {
 func (stmt *WhereStmt) OrWhere(w *WhereStmt) *WhereStmt {
 	return stmt.addWhere(false, w)
 }
+
+// 转义 LIKE 语句中的特殊字符，对应的转义字符为 likeEscape。
+var likeReplacer = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+const likeEscape = "!"
+
+// EscapeLike 转义 s 中的 % 和 _ 等 LIKE 语句中的特殊字符，
+// 转义之后的内容可以安全地与通配符拼接，作为 AndLike() 等函数的参数：
+//  stmt.AndLike("{name}", "%"+sqlbuilder.EscapeLike(keyword)+"%")
+func EscapeLike(s string) string {
+	return likeReplacer.Replace(s)
+}
+
+// 将仅包含一个切片的 v 展开，[]byte 会被当作普通的值。
+func expandArgs(v []interface{}) []interface{} {
+	if len(v) != 1 {
+		return v
+	}
+
+	if _, ok := v[0].([]byte); ok {
+		return v
+	}
+
+	rval := reflect.ValueOf(v[0])
+	if rval.Kind() != reflect.Slice && rval.Kind() != reflect.Array {
+		return v
+	}
+
+	ret := make([]interface{}, 0, rval.Len())
+	for i := 0; i < rval.Len(); i++ {
+		ret = append(ret, rval.Index(i).Interface())
+	}
+	return ret
+}
+
+// 以子查询 (sub) 作为条件的一部分，prefix 为子查询之前的内容，比如 col IN。
+func (stmt *WhereStmt) subquery(and bool, prefix string, sub *SelectStmt) *WhereStmt {
+	query, args, err := subquery(sub)
+	if err != nil {
+		stmt.err = err
+		return stmt
+	}
+	return stmt.where(and, prefix+query, args...)
+}
+
+func (stmt *WhereStmt) in(and, not bool, col string, v []interface{}) *WhereStmt {
+	if len(v) == 1 {
+		if sub, ok := v[0].(*SelectStmt); ok {
+			if not {
+				return stmt.subquery(and, col+" NOT IN", sub)
+			}
+			return stmt.subquery(and, col+" IN", sub)
+		}
+	}
+
+	v = expandArgs(v)
+	if len(v) == 0 { // IN () 是错误的语法，直接使用恒定的条件代替。
+		if not {
+			return stmt.where(and, "1=1")
+		}
+		return stmt.where(and, "1=0")
+	}
+
+	cond := New(col)
+	if not {
+		cond.WriteString(" NOT")
+	}
+	cond.WriteString(" IN(")
+	for range v {
+		cond.WriteString("?,")
+	}
+	cond.TruncateLast(1).WriteByte(')')
+
+	return stmt.where(and, cond.String(), v...)
+}
+
+// AndIn 指定 AND col IN(v...) 语句
+//
+// v 可以是多个值，也可以是一个切片；v 为空时，条件始终不成立。
+// v 也可以是一个 *SelectStmt，此时生成 col IN(SELECT ...) 形式的子查询。
+func (stmt *WhereStmt) AndIn(col string, v ...interface{}) *WhereStmt {
+	return stmt.in(true, false, col, v)
+}
+
+// OrIn 指定 OR col IN(v...) 语句，具体说明可参考 AndIn()。
+func (stmt *WhereStmt) OrIn(col string, v ...interface{}) *WhereStmt {
+	return stmt.in(false, false, col, v)
+}
+
+// AndNotIn 指定 AND col NOT IN(v...) 语句
+//
+// v 可以是多个值，也可以是一个切片；v 为空时，条件始终成立。
+// v 也可以是一个 *SelectStmt，此时生成 col NOT IN(SELECT ...) 形式的子查询。
+func (stmt *WhereStmt) AndNotIn(col string, v ...interface{}) *WhereStmt {
+	return stmt.in(true, true, col, v)
+}
+
+// OrNotIn 指定 OR col NOT IN(v...) 语句，具体说明可参考 AndNotIn()。
+func (stmt *WhereStmt) OrNotIn(col string, v ...interface{}) *WhereStmt {
+	return stmt.in(false, true, col, v)
+}
+
+// AndExists 指定 AND EXISTS(sub) 语句
+func (stmt *WhereStmt) AndExists(sub *SelectStmt) *WhereStmt {
+	return stmt.subquery(true, "EXISTS", sub)
+}
+
+// OrExists 指定 OR EXISTS(sub) 语句
+func (stmt *WhereStmt) OrExists(sub *SelectStmt) *WhereStmt {
+	return stmt.subquery(false, "EXISTS", sub)
+}
+
+// AndNotExists 指定 AND NOT EXISTS(sub) 语句
+func (stmt *WhereStmt) AndNotExists(sub *SelectStmt) *WhereStmt {
+	return stmt.subquery(true, "NOT EXISTS", sub)
+}
+
+// OrNotExists 指定 OR NOT EXISTS(sub) 语句
+func (stmt *WhereStmt) OrNotExists(sub *SelectStmt) *WhereStmt {
+	return stmt.subquery(false, "NOT EXISTS", sub)
+}
+
+// AndBetween 指定 AND col BETWEEN v1 AND v2 语句
+func (stmt *WhereStmt) AndBetween(col string, v1, v2 interface{}) *WhereStmt {
+	return stmt.where(true, col+" BETWEEN ? AND ?", v1, v2)
+}
+
+// OrBetween 指定 OR col BETWEEN v1 AND v2 语句
+func (stmt *WhereStmt) OrBetween(col string, v1, v2 interface{}) *WhereStmt {
+	return stmt.where(false, col+" BETWEEN ? AND ?", v1, v2)
+}
+
+// AndLike 指定 AND col LIKE pattern 语句
+//
+// pattern 中的 % 和 _ 为通配符，! 为转义字符，
+// 来自用户输入的内容应该先经过 EscapeLike() 处理。
+func (stmt *WhereStmt) AndLike(col string, pattern string) *WhereStmt {
+	return stmt.where(true, col+" LIKE ? ESCAPE '"+likeEscape+"'", pattern)
+}
+
+// OrLike 指定 OR col LIKE pattern 语句，具体说明可参考 AndLike()。
+func (stmt *WhereStmt) OrLike(col string, pattern string) *WhereStmt {
+	return stmt.where(false, col+" LIKE ? ESCAPE '"+likeEscape+"'", pattern)
+}
+
+// AndIsNull 指定 AND col IS NULL 语句
+func (stmt *WhereStmt) AndIsNull(col string) *WhereStmt {
+	return stmt.where(true, col+" IS NULL")
+}
+
+// OrIsNull 指定 OR col IS NULL 语句
+func (stmt *WhereStmt) OrIsNull(col string) *WhereStmt {
+	return stmt.where(false, col+" IS NULL")
+}
+
+// AndNotNull 指定 AND col IS NOT NULL 语句
+func (stmt *WhereStmt) AndNotNull(col string) *WhereStmt {
+	return stmt.where(true, col+" IS NOT NULL")
+}
+
+// OrNotNull 指定 OR col IS NOT NULL 语句
+func (stmt *WhereStmt) OrNotNull(col string) *WhereStmt {
+	return stmt.where(false, col+" IS NOT NULL")
+}
+
+// AndEq 指定 AND col=v 语句
+func (stmt *WhereStmt) AndEq(col string, v interface{}) *WhereStmt {
+	return stmt.where(true, col+"=?", v)
+}
+
+// OrEq 指定 OR col=v 语句
+func (stmt *WhereStmt) OrEq(col string, v interface{}) *WhereStmt {
+	return stmt.where(false, col+"=?", v)
+}
+
+// AndNeq 指定 AND col<>v 语句
+func (stmt *WhereStmt) AndNeq(col string, v interface{}) *WhereStmt {
+	return stmt.where(true, col+"<>?", v)
+}
+
+// OrNeq 指定 OR col<>v 语句
+func (stmt *WhereStmt) OrNeq(col string, v interface{}) *WhereStmt {
+	return stmt.where(false, col+"<>?", v)
+}
+
+// AndGt 指定 AND col>v 语句
+func (stmt *WhereStmt) AndGt(col string, v interface{}) *WhereStmt {
+	return stmt.where(true, col+">?", v)
+}
+
+// OrGt 指定 OR col>v 语句
+func (stmt *WhereStmt) OrGt(col string, v interface{}) *WhereStmt {
+	return stmt.where(false, col+">?", v)
+}
+
+// AndGte 指定 AND col>=v 语句
+func (stmt *WhereStmt) AndGte(col string, v interface{}) *WhereStmt {
+	return stmt.where(true, col+">=?", v)
+}
+
+// OrGte 指定 OR col>=v 语句
+func (stmt *WhereStmt) OrGte(col string, v interface{}) *WhereStmt {
+	return stmt.where(false, col+">=?", v)
+}
+
+// AndLt 指定 AND col<v 语句
+func (stmt *WhereStmt) AndLt(col string, v interface{}) *WhereStmt {
+	return stmt.where(true, col+"<?", v)
+}
+
+// OrLt 指定 OR col<v 语句
+func (stmt *WhereStmt) OrLt(col string, v interface{}) *WhereStmt {
+	return stmt.where(false, col+"<?", v)
+}
+
+// AndLte 指定 AND col<=v 语句
+func (stmt *WhereStmt) AndLte(col string, v interface{}) *WhereStmt {
+	return stmt.where(true, col+"<=?", v)
+}
+
+// OrLte 指定 OR col<=v 语句
+func (stmt *WhereStmt) OrLte(col string, v interface{}) *WhereStmt {
+	return stmt.where(false, col+"<=?", v)
+}
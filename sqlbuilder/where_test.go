@@ -69,3 +69,70 @@ func TestWhere_addWhere(t *testing.T) {
 	a.Equal(args, []interface{}{2, 3, 4, 4})
 	sqltest.Equal(a, query, "(id=? or id=? or(id=?)) or (id=?)")
 }
+
+func TestWhere_helpers(t *testing.T) {
+	a := assert.New(t)
+	w := newWhereStmt()
+
+	w.AndIn("id", 1, 2, 3).
+		OrNotIn("id", []int64{4, 5}).
+		AndBetween("age", 18, 30).
+		AndLike("name", "%"+EscapeLike("50%_!")+"%").
+		AndIsNull("deleted").
+		OrNotNull("updated").
+		AndEq("a", 1).
+		AndNeq("b", 2).
+		AndGt("c", 3).
+		OrGte("d", 4).
+		AndLt("e", 5).
+		OrLte("f", 6)
+	query, args, err := w.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{1, 2, 3, int64(4), int64(5), 18, 30, "%50!%!_!!%", 1, 2, 3, 4, 5, 6})
+	sqltest.Equal(a, query, "id IN(?,?,?) OR id NOT IN(?,?) AND age BETWEEN ? AND ? "+
+		"AND name LIKE ? ESCAPE '!' AND deleted IS NULL OR updated IS NOT NULL "+
+		"AND a=? AND b<>? AND c>? OR d>=? AND e<? OR f<=?")
+
+	// 空列表
+	w.Reset()
+	w.AndIn("id").OrNotIn("id", []string{})
+	query, args, err = w.SQL()
+	a.NotError(err).Empty(args)
+	sqltest.Equal(a, query, "1=0 OR 1=1")
+
+	// []byte 不展开
+	w.Reset()
+	w.AndIn("id", []byte("abc"))
+	query, args, err = w.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{[]byte("abc")})
+	sqltest.Equal(a, query, "id IN(?)")
+}
+
+func TestWhere_subquery(t *testing.T) {
+	a := assert.New(t)
+	w := newWhereStmt()
+
+	sub := Select(nil, nil).Select("uid").From("bans").Where("expired>?", 5)
+	w.And("id>?", 1).OrIn("id", sub).AndNotExists(sub)
+	query, args, err := w.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{1, 5, 5})
+	sqltest.Equal(a, query, "id>? or id in(select uid from bans where expired>?) and not exists(select uid from bans where expired>?)")
+
+	// 子查询出错
+	w.Reset()
+	w.AndExists(Select(nil, nil).Select("*"))
+	query, args, err = w.SQL()
+	a.Equal(err, ErrTableIsEmpty).Empty(query).Nil(args)
+
+	// 子条件中的子查询出错
+	w.Reset()
+	w.And("id>?", 1).AndWhere(newWhereStmt().OrIn("id", Select(nil, nil).Select("uid")))
+	query, args, err = w.SQL()
+	a.Equal(err, ErrTableIsEmpty).Empty(query).Nil(args)
+
+	w.Reset()
+	_, _, err = w.SQL()
+	a.NotError(err)
+}
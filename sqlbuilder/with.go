@@ -0,0 +1,82 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlbuilder
+
+// WITH 子句，即公用表表达式(CTE)
+//
+// 可以附加在 SelectStmt、UpdateStmt 和 DeleteStmt 之前，
+// 其参数会排在所附加语句的参数之前。
+type withClause struct {
+	recursive bool
+	ctes      []*cte
+}
+
+type cte struct {
+	name  string
+	cols  []string
+	query SQLer
+}
+
+func (w *withClause) add(recursive bool, name string, query SQLer, cols []string) {
+	if recursive {
+		w.recursive = true
+	}
+
+	w.ctes = append(w.ctes, &cte{
+		name:  name,
+		cols:  cols,
+		query: query,
+	})
+}
+
+func (w *withClause) reset() {
+	w.recursive = false
+	w.ctes = w.ctes[:0]
+}
+
+// 生成 WITH ... 语句，未指定任何 CTE 时返回空值，否则以空格结尾。
+//
+// 各 CTE 的内容在调用时才生成。
+func (w *withClause) SQL() (string, []interface{}, error) {
+	if len(w.ctes) == 0 {
+		return "", nil, nil
+	}
+
+	buf := New("WITH ")
+	if w.recursive {
+		buf.WriteString("RECURSIVE ")
+	}
+
+	args := make([]interface{}, 0, 10)
+	for _, c := range w.ctes {
+		if c.name == "" {
+			return "", nil, ErrTableIsEmpty
+		}
+
+		query, vals, err := c.query.SQL()
+		if err != nil {
+			return "", nil, err
+		}
+
+		buf.WriteString(c.name)
+		if len(c.cols) > 0 {
+			buf.WriteByte('(')
+			for _, col := range c.cols {
+				buf.WriteString(col)
+				buf.WriteByte(',')
+			}
+			buf.TruncateLast(1)
+			buf.WriteByte(')')
+		}
+		buf.WriteString(" AS (")
+		buf.WriteString(query)
+		buf.WriteString("),")
+		args = append(args, vals...)
+	}
+	buf.TruncateLast(1)
+	buf.WriteByte(' ')
+
+	return buf.String(), args, nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package sqlbuilder_test
+
+import (
+	"testing"
+
+	"github.com/issue9/orm"
+	"github.com/issue9/orm/dialect"
+	"github.com/issue9/orm/internal/sqltest"
+	"github.com/issue9/orm/sqlbuilder"
+
+	"github.com/issue9/assert"
+)
+
+func TestWith(t *testing.T) {
+	a := assert.New(t)
+	d := dialect.Sqlite3()
+
+	// 递归
+	anchor := sqlbuilder.Select(nil, d).Select("id", "parent").From("cats").Where("id=?", 1)
+	recursive := sqlbuilder.Select(nil, d).Select("c.id", "c.parent").From("cats AS c").
+		Join("INNER", "tree AS t", "c.parent=t.id").
+		Where("c.state=?", 2)
+	tree := sqlbuilder.Compound(nil, d, anchor).UnionAll(recursive)
+	s := sqlbuilder.Select(nil, d).
+		WithRecursive("tree", tree, "id", "parent").
+		With("top", sqlbuilder.Select(nil, d).Select("id").From("cats").Where("parent=?", 0)).
+		Select("id").From("tree").Where("id>?", 3)
+	query, args, err := s.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{1, 2, 0, 3})
+	sqltest.Equal(a, query, "with recursive tree(id,parent) as ("+
+		"select id,parent from cats where id=? union all "+
+		"select c.id,c.parent from cats as c inner join tree as t on c.parent=t.id where c.state=?), "+
+		"top as (select id from cats where parent=?) "+
+		"select id from tree where id>?")
+
+	// update
+	sub := sqlbuilder.Select(nil, d).Select("id").From("cats").Where("state=?", 5)
+	u := sqlbuilder.Update(nil).With("old", sub).Table("cats").Set("state", 6).Where("id IN(SELECT id FROM old) AND parent=?", 7)
+	query, args, err = u.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{5, 6, 7})
+	sqltest.Equal(a, query, "with old as (select id from cats where state=?) update cats set state=? where id in(select id from old) and parent=?")
+
+	// postgres 按顺序转换成 $N
+	for _, dd := range []sqlbuilder.Dialect{dialect.Mysql(), dialect.Sqlite3(), dialect.Postgres()} {
+		q, vals, err := dd.SQL(query, args)
+		a.NotError(err).Equal(vals, []interface{}{5, 6, 7})
+		if dd == dialect.Postgres() {
+			sqltest.Equal(a, q, "with old as (select id from cats where state=$1) update cats set state=$2 where id in(select id from old) and parent=$3")
+		}
+	}
+
+	// delete
+	del := sqlbuilder.Delete(nil).With("old", sub, "id").Table("cats").Where("id IN(SELECT id FROM old) AND parent=?", 8)
+	query, args, err = del.SQL()
+	a.NotError(err)
+	a.Equal(args, []interface{}{5, 8})
+	sqltest.Equal(a, query, "with old(id) as (select id from cats where state=?) delete from cats where id in(select id from old) and parent=?")
+
+	del.Reset()
+	query, args, err = del.Table("cats").Where("id=?", 1).SQL()
+	a.NotError(err).Equal(args, []interface{}{1})
+	sqltest.Equal(a, query, "delete from cats where id=?")
+
+	// CTE 出错
+	s = sqlbuilder.Select(nil, d).With("x", sqlbuilder.Select(nil, d).From("cats")).Select("*").From("x")
+	query, args, err = s.SQL()
+	a.Equal(err, sqlbuilder.ErrColumnsIsEmpty).Empty(query).Nil(args)
+}
+
+func TestWith_Query(t *testing.T) {
+	a := assert.New(t)
+	e, err := orm.NewDB("sqlite3", "./test.db", "test_", dialect.Sqlite3())
+	a.NotError(err)
+	defer func() {
+		_, err = e.Exec("DROP TABLE IF EXISTS #cats")
+		a.NotError(err)
+		a.NotError(e.Close())
+	}()
+
+	_, err = e.Exec("DROP TABLE IF EXISTS #cats")
+	a.NotError(err)
+	_, err = e.Exec("CREATE TABLE #cats({id} INTEGER NOT NULL,{parent} INTEGER NOT NULL)")
+	a.NotError(err)
+	// 1 -> 2 -> 3 -> 4，5 独立
+	for _, c := range [][2]int{{1, 0}, {2, 1}, {3, 2}, {4, 3}, {5, 0}} {
+		_, err = e.Exec("INSERT INTO #cats({id},{parent}) VALUES(?,?)", c[0], c[1])
+		a.NotError(err)
+	}
+
+	tree := func() sqlbuilder.SQLer {
+		anchor := sqlbuilder.Select(e, e.Dialect()).Select("{id}").From("#cats").Where("{id}=?", 2)
+		children := sqlbuilder.Select(e, e.Dialect()).Select("c.{id}").From("#cats AS c").
+			Join("INNER", "tree AS t", "c.{parent}=t.{id}")
+		return sqlbuilder.Compound(e, e.Dialect(), anchor).UnionAll(children)
+	}
+
+	cnt, err := sqlbuilder.Select(e, e.Dialect()).WithRecursive("tree", tree(), "id").
+		Count("COUNT(*) AS cnt").From("tree").
+		QueryInt("cnt")
+	a.NotError(err).Equal(cnt, 3)
+
+	// 删除 2 及其所有子节点
+	r, err := sqlbuilder.Delete(e).WithRecursive("tree", tree(), "id").Table("#cats").
+		Where("{id} IN(SELECT {id} FROM tree)").Exec()
+	a.NotError(err)
+	rows, err := r.RowsAffected()
+	a.NotError(err).Equal(rows, 3)
+}
@@ -0,0 +1,226 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 预编译语句的缓存，按最近最少使用的规则淘汰。
+type stmtCache struct {
+	mu    sync.Mutex
+	size  int
+	list  *list.List // 最近使用的在前
+	items map[stmtKey]*list.Element
+}
+
+// 预编译语句与 sql.DB 相关，所以主从数据库中的相同语句需要分开缓存。
+type stmtKey struct {
+	db    *sql.DB
+	query string
+}
+
+type cachedStmt struct {
+	key  stmtKey
+	stmt *sql.Stmt
+
+	// 正在使用该语句的数量，被淘汰的语句需要等到不再使用时才关闭。
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:  size,
+		list:  list.New(),
+		items: make(map[stmtKey]*list.Element, size),
+	}
+}
+
+// SetStmtCache 启用预编译语句的缓存，最多缓存 size 条语句，
+// 超出数量时，会关闭并淘汰最近最少使用的语句。size 小于等于 0 表示禁用缓存。
+//
+// 启用之后，通过 Query() 和 Exec() 执行的语句，包括各类模型操作以及
+// sqlbuilder 中各语句的执行，都会以替换占位符之后的最终语句为键名，
+// 从缓存中获取预编译语句执行；在事务中执行时，则会通过 sql.Tx.Stmt()
+// 将缓存的语句绑定到该事务，同一事务中的相同语句仅绑定一次，在事务结束时关闭。
+// Prepare() 返回的语句由调用方负责关闭，不会被缓存。
+//
+// 非协程安全，应该在初始化 DB 之后，执行其它语句之前调用。
+func (db *DB) SetStmtCache(size int) error {
+	if db.stmts != nil {
+		if err := db.stmts.close(); err != nil {
+			return err
+		}
+		db.stmts = nil
+	}
+
+	if size > 0 {
+		db.stmts = newStmtCache(size)
+	}
+
+	return nil
+}
+
+// 获取 e 中与 query 对应的预编译语句，使用完之后需要调用 release 释放。
+//
+// 未启用缓存，或是 e 不支持缓存时，返回的 stmt 为 nil。
+func (db *DB) cachedStmt(ctx context.Context, e stdEngine, query string) (stmt *sql.Stmt, release func(), err error) {
+	if db.stmts == nil || !cacheable(query) {
+		return nil, nil, nil
+	}
+
+	std, ok := e.(*sql.DB)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	cs, err := db.stmts.get(ctx, db, std, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cs.stmt, func() { db.stmts.release(cs) }, nil
+}
+
+// 获取事务中与 query 对应的预编译语句
+//
+// 缓存的语句通过 sql.Tx.Stmt() 绑定到事务之后，保存在顶层事务中，
+// 同一事务中相同的语句只绑定一次，并在事务结束时统一关闭。
+// 未启用缓存时，返回 nil。
+func (tx *Tx) cachedStmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	root := tx.root
+	root.stmtsMu.Lock()
+	defer root.stmtsMu.Unlock()
+
+	if stmt, found := root.stmts[query]; found {
+		return stmt, nil
+	}
+
+	cs, release, err := tx.db.cachedStmt(ctx, tx.db.stdDB, query) // 事务只会在主数据库上执行
+	if err != nil || cs == nil {
+		return nil, err
+	}
+	// 在绑定到事务的语句关闭之前，cs 也不会真正被关闭，可以直接释放。
+	defer release()
+
+	if root.stmts == nil {
+		root.stmts = make(map[string]*sql.Stmt, 10)
+	}
+	stmt := tx.stdTx.StmtContext(ctx, cs)
+	root.stmts[query] = stmt
+	return stmt, nil
+}
+
+// 关闭所有绑定到事务的语句，仅顶层事务调用。
+func (tx *Tx) closeStmts() {
+	tx.stmtsMu.Lock()
+	defer tx.stmtsMu.Unlock()
+
+	for _, stmt := range tx.stmts {
+		stmt.Close()
+	}
+	tx.stmts = nil
+}
+
+// 仅缓存 DML 语句，DDL 以及保存点等语句，部分数据库并不支持预编译。
+func cacheable(query string) bool {
+	query = strings.TrimSpace(query)
+	if len(query) < 6 {
+		return false
+	}
+
+	switch strings.ToUpper(query[:6]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "REPLAC":
+		return true
+	default:
+		return strings.HasPrefix(strings.ToUpper(query[:5]), "WITH ")
+	}
+}
+
+// 获取缓存的语句，若不存在，则在 std 上预编译该语句并缓存。
+func (c *stmtCache) get(ctx context.Context, db *DB, std *sql.DB, query string) (*cachedStmt, error) {
+	key := stmtKey{db: std, query: query}
+
+	c.mu.Lock()
+	if elem, found := c.items[key]; found {
+		c.list.MoveToFront(elem)
+		cs := elem.Value.(*cachedStmt)
+		cs.refs++
+		c.mu.Unlock()
+		return cs, nil
+	}
+	c.mu.Unlock()
+
+	start := time.Now()
+	stmt, err := std.PrepareContext(ctx, query)
+	if len(db.interceptors) > 0 {
+		db.intercept(ctx, std, EventPrepare, query, nil, start, -1, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 预编译期间，其它协程可能已经缓存了相同的语句
+	if elem, found := c.items[key]; found {
+		c.list.MoveToFront(elem)
+		cs := elem.Value.(*cachedStmt)
+		cs.refs++
+		return cs, stmt.Close()
+	}
+
+	cs := &cachedStmt{key: key, stmt: stmt, refs: 1}
+	c.items[key] = c.list.PushFront(cs)
+
+	for c.list.Len() > c.size {
+		c.evict(c.list.Back())
+	}
+
+	return cs, nil
+}
+
+// 释放对 cs 的引用，若该语句已经被淘汰且不再被使用，则关闭该语句。
+func (c *stmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cs.refs--
+	if cs.evicted && cs.refs == 0 {
+		cs.stmt.Close()
+	}
+}
+
+// 淘汰 elem 对应的语句，调用方需要持有锁。
+func (c *stmtCache) evict(elem *list.Element) error {
+	cs := c.list.Remove(elem).(*cachedStmt)
+	delete(c.items, cs.key)
+
+	cs.evicted = true
+	if cs.refs == 0 {
+		return cs.stmt.Close()
+	}
+	return nil
+}
+
+// 淘汰所有的缓存
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for c.list.Len() > 0 {
+		if e := c.evict(c.list.Back()); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/issue9/assert"
+	"github.com/issue9/orm"
+	"github.com/issue9/orm/internal/modeltest"
+)
+
+func TestDB_SetStmtCache(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer clearData(db, a)
+	a.NotError(db.Create(&modeltest.User{}))
+	a.NotError(db.SetStmtCache(2))
+
+	prepares := 0
+	db.AddInterceptor(orm.InterceptorFunc(func(ctx context.Context, e *orm.Event) {
+		if e.Type == orm.EventPrepare {
+			prepares++
+		}
+	}))
+
+	// 相同的语句仅预编译一次
+	for i := 0; i < 3; i++ {
+		_, err := db.Insert(&modeltest.User{Username: "u" + string(rune('1'+i))})
+		a.NotError(err)
+	}
+	a.Equal(prepares, 1)
+
+	u := &modeltest.User{ID: 1}
+	a.NotError(db.Select(u))
+	a.Equal(u.Username, "u1")
+	a.Equal(prepares, 2)
+
+	// 事务中复用缓存的语句，嵌套事务与顶层事务共用绑定的语句
+	tx, err := db.Begin()
+	a.NotError(err)
+	u = &modeltest.User{ID: 2}
+	a.NotError(tx.Select(u))
+	a.Equal(u.Username, "u2")
+	nested, err := tx.Begin()
+	a.NotError(err)
+	for i := 0; i < 3; i++ {
+		u = &modeltest.User{ID: 2}
+		a.NotError(nested.Select(u))
+		a.Equal(u.Username, "u2")
+	}
+	a.NotError(nested.Commit())
+	a.NotError(tx.Commit())
+	a.Equal(prepares, 2)
+
+	// 事务结束之后，缓存的语句依然可用
+	tx, err = db.Begin()
+	a.NotError(err)
+	u = &modeltest.User{ID: 1}
+	a.NotError(tx.Select(u))
+	a.Equal(u.Username, "u1")
+	a.NotError(tx.Rollback())
+	u = &modeltest.User{ID: 1}
+	a.NotError(db.Select(u))
+	a.Equal(prepares, 2)
+
+	// 超出数量，淘汰最早的 INSERT 语句
+	cnt, err := db.Count(&modeltest.User{Username: "u3"})
+	a.NotError(err).Equal(cnt, 1)
+	a.Equal(prepares, 3)
+	_, err = db.Insert(&modeltest.User{Username: "u4"})
+	a.NotError(err)
+	a.Equal(prepares, 4)
+
+	// DDL 不会被缓存
+	a.NotError(db.Drop(&modeltest.User{}))
+	a.NotError(db.Create(&modeltest.User{}))
+	a.Equal(prepares, 4)
+
+	// 禁用缓存
+	a.NotError(db.SetStmtCache(0))
+	_, err = db.Insert(&modeltest.User{Username: "u5"})
+	a.NotError(err)
+	a.Equal(prepares, 4)
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 by caixw, All rights reserved.
+// Use of this source code is governed by a MIT
+// license that can be found in the LICENSE file.
+
+package orm
+
+import (
+	"bytes"
+	"reflect"
+	"time"
+
+	"github.com/issue9/orm/model"
+)
+
+// Tracker 实现此接口的对象，在通过 Select() 或是 ForUpdate() 读取之后，
+// 会保存一份各列值的快照。之后调用 Update() 时，仅更新与快照相比有变化的列，
+// 包括被修改为零值的列；若没有任何变化，则返回 ErrNoChanges。
+//
+// 一般情况下，直接在对象中嵌入 Tracked 即可：
+//  type User struct {
+//      orm.Tracked
+//      ID   int64  `orm:"name(id);ai"`
+//      Name string `orm:"name(name)"`
+//  }
+type Tracker interface {
+	// 获取快照，若从未保存过快照，则返回 nil。
+	Snapshot() map[string]interface{}
+
+	// 保存快照，键名为列名。
+	SetSnapshot(map[string]interface{})
+}
+
+// Tracked 是 Tracker 接口的默认实现，可直接嵌入到对象中使用。
+type Tracked struct {
+	snapshot map[string]interface{}
+}
+
+// Snapshot 获取快照
+func (t *Tracked) Snapshot() map[string]interface{} {
+	return t.snapshot
+}
+
+// SetSnapshot 保存快照
+func (t *Tracked) SetSnapshot(snapshot map[string]interface{}) {
+	t.snapshot = snapshot
+}
+
+// 若 v 实现了 Tracker 接口，则保存 rval 中各列的值作为快照。
+func takeSnapshot(v interface{}, m *model.Model, rval reflect.Value) {
+	t, ok := v.(Tracker)
+	if !ok {
+		return
+	}
+
+	snapshot := make(map[string]interface{}, len(m.Cols))
+	for name, col := range m.Cols {
+		val := rval.FieldByName(col.GoName).Interface()
+		if bs, ok := val.([]byte); ok { // 切片共享底层数组，需要复制一份。
+			val = append([]byte(nil), bs...)
+		}
+		snapshot[name] = val
+	}
+	t.SetSnapshot(snapshot)
+}
+
+// 获取 v 的快照，若 v 未实现 Tracker 或是不存在快照，则返回 nil。
+func getSnapshot(v interface{}) map[string]interface{} {
+	if t, ok := v.(Tracker); ok {
+		return t.Snapshot()
+	}
+	return nil
+}
+
+// 比较当前值 val 与快照中的值 old 是否相同。
+func sameValue(val, old interface{}) bool {
+	switch v := val.(type) {
+	case time.Time:
+		o, ok := old.(time.Time)
+		return ok && v.Equal(o)
+	case []byte:
+		o, ok := old.([]byte)
+		return ok && bytes.Equal(v, o)
+	default:
+		return reflect.DeepEqual(val, old)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"context"
 	"database/sql"
 	"reflect"
+	"strconv"
+	"sync"
 
 	"github.com/issue9/orm/fetch"
 )
@@ -17,11 +19,28 @@ type Tx struct {
 	db    *DB
 	stdTx *sql.Tx
 	sql   *SQL
+
+	// 通过 Tx.Begin() 声明的嵌套事务，会以保存点的形式实现，
+	// savepoint 即为该保存点的名称，顶层事务则为空值。
+	savepoint string
+	root      *Tx // 顶层事务
+	spID      int // 用于生成保存点名称，仅顶层事务使用
+
+	// 绑定到事务的缓存语句，以语句为键名，仅顶层事务使用。
+	stmts   map[string]*sql.Stmt
+	stmtsMu sync.Mutex
 }
 
 // Begin 开始一个新的事务
 func (db *DB) Begin() (*Tx, error) {
-	tx, err := db.stdDB.Begin()
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx 开始一个新的事务
+//
+// opts 用于指定事务的隔离级别以及是否只读，为 nil 表示采用数据库的默认值。
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.stdDB.BeginTx(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -31,10 +50,36 @@ func (db *DB) Begin() (*Tx, error) {
 		stdTx: tx,
 	}
 	inst.sql = &SQL{engine: inst}
+	inst.root = inst
 
 	return inst, nil
 }
 
+// DoTransaction 在事务中执行 f 函数
+//
+// f 返回 nil 时提交事务，返回错误时回滚事务，并将该错误返回；
+// 若 f 中发生了 panic，则回滚事务之后，再次抛出该 panic。
+func (db *DB) DoTransaction(ctx context.Context, opts *sql.TxOptions, f func(tx *Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if msg := recover(); msg != nil {
+			tx.Rollback()
+			panic(msg)
+		}
+	}()
+
+	if err = f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
 // StdTx 返回标准库的 *sql.Tx 对象。
 func (tx *Tx) StdTx() *sql.Tx {
 	return tx.stdTx
@@ -42,68 +87,70 @@ func (tx *Tx) StdTx() *sql.Tx {
 
 // Query 执行一条查询语句。
 func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	query = tx.db.replacer.Replace(query)
-	query, err := tx.db.dialect.SQL(query)
-	if err != nil {
-		return nil, err
-	}
-
-	return tx.stdTx.Query(query, args...)
+	return tx.QueryContext(context.Background(), query, args...)
 }
 
 // QueryContext 执行一条查询语句。
 func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	query = tx.db.replacer.Replace(query)
-	query, err := tx.db.dialect.SQL(query)
+	query, args, err := tx.db.translate(query, args)
 	if err != nil {
 		return nil, err
 	}
 
-	return tx.stdTx.QueryContext(ctx, query, args...)
-}
-
-// Exec 执行一条 SQL 语句。
-func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
-	query = tx.db.replacer.Replace(query)
-	query, err := tx.db.dialect.SQL(query)
+	stmt, err := tx.cachedStmt(ctx, query)
 	if err != nil {
 		return nil, err
 	}
+	return tx.db.queryStmt(ctx, tx.stdTx, stmt, query, args)
+}
 
-	return tx.stdTx.Exec(query, args...)
+// Exec 执行一条 SQL 语句。
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.ExecContext(context.Background(), query, args...)
 }
 
 // ExecContext 执行一条 SQL 语句。
 func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	query = tx.db.replacer.Replace(query)
-	query, err := tx.db.dialect.SQL(query)
+	query, args, err := tx.db.translate(query, args)
 	if err != nil {
 		return nil, err
 	}
 
-	return tx.stdTx.ExecContext(ctx, query, args...)
-}
-
-// Prepare 将一条 SQL 语句进行预编译。
-func (tx *Tx) Prepare(query string) (*sql.Stmt, error) {
-	query = tx.db.replacer.Replace(query)
-	query, err := tx.db.dialect.SQL(query)
+	stmt, err := tx.cachedStmt(ctx, query)
 	if err != nil {
 		return nil, err
 	}
+	return tx.db.execStmt(ctx, tx.stdTx, stmt, query, args)
+}
 
-	return tx.stdTx.Prepare(query)
+// Prepare 将一条 SQL 语句进行预编译。
+func (tx *Tx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.PrepareContext(context.Background(), query)
 }
 
 // PrepareContext 将一条 SQL 语句进行预编译。
 func (tx *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
-	query = tx.db.replacer.Replace(query)
-	query, err := tx.db.dialect.SQL(query)
+	return tx.db.prepare(ctx, tx.stdTx, query)
+}
+
+// 通过由 tx.PrepareContext(query) 返回的 stmt 执行语句，
+// 与 ExecContext() 相同，args 会经过转换，并触发拦截器。
+func (tx *Tx) execPrepared(ctx context.Context, stmt *sql.Stmt, query string, args []interface{}) (sql.Result, error) {
+	query, args, err := tx.db.translate(query, args)
 	if err != nil {
 		return nil, err
 	}
+	return tx.db.execStmt(ctx, tx.stdTx, stmt, query, args)
+}
 
-	return tx.stdTx.PrepareContext(ctx, query)
+// 通过由 tx.PrepareContext(query) 返回的 stmt 执行查询，
+// 与 QueryContext() 相同，args 会经过转换，并触发拦截器。
+func (tx *Tx) queryPrepared(ctx context.Context, stmt *sql.Stmt, query string, args []interface{}) (*sql.Rows, error) {
+	query, args, err := tx.db.translate(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return tx.db.queryStmt(ctx, tx.stdTx, stmt, query, args)
 }
 
 // Dialect 返回对应的 Dialect 实例
@@ -114,30 +161,139 @@ func (tx *Tx) Dialect() Dialect {
 // Commit 提交事务。
 //
 // 提交之后，整个 Tx 对象将不再有效。
+// 若是由 Tx.Begin() 声明的嵌套事务，则仅释放其对应的保存点。
 func (tx *Tx) Commit() error {
+	if tx.savepoint != "" {
+		return tx.Release(tx.savepoint)
+	}
+
+	defer tx.closeStmts()
 	return tx.stdTx.Commit()
 }
 
 // Rollback 回滚事务。
 //
 // 回滚之后，整个 Tx 对象将不再有效。
+// 若是由 Tx.Begin() 声明的嵌套事务，则仅回滚到其对应的保存点。
 func (tx *Tx) Rollback() error {
+	if tx.savepoint != "" {
+		if err := tx.RollbackTo(tx.savepoint); err != nil {
+			return err
+		}
+		return tx.Release(tx.savepoint)
+	}
+
+	defer tx.closeStmts()
 	return tx.stdTx.Rollback()
 }
 
+// Begin 在当前事务中开始一个嵌套事务。
+//
+// 嵌套事务通过保存点实现，与当前事务共享同一个 sql.Tx 实例。
+// 嵌套事务的 Commit() 和 Rollback() 仅作用于其对应的保存点，
+// 最终是否提交，依然由顶层事务决定。
+func (tx *Tx) Begin() (*Tx, error) {
+	tx.root.spID++
+	name := "orm_sp_" + strconv.Itoa(tx.root.spID)
+	if err := tx.Savepoint(name); err != nil {
+		return nil, err
+	}
+
+	inst := &Tx{
+		db:        tx.db,
+		stdTx:     tx.stdTx,
+		savepoint: name,
+		root:      tx.root,
+	}
+	inst.sql = &SQL{engine: inst}
+
+	return inst, nil
+}
+
+// Savepoint 在当前事务中创建一个名为 name 的保存点。
+func (tx *Tx) Savepoint(name string) error {
+	_, err := tx.Exec("SAVEPOINT {" + name + "}")
+	return err
+}
+
+// RollbackTo 回滚到名为 name 的保存点。
+//
+// 回滚之后，该保存点依然有效。
+func (tx *Tx) RollbackTo(name string) error {
+	_, err := tx.Exec("ROLLBACK TO SAVEPOINT {" + name + "}")
+	return err
+}
+
+// Release 释放名为 name 的保存点。
+func (tx *Tx) Release(name string) error {
+	_, err := tx.Exec("RELEASE SAVEPOINT {" + name + "}")
+	return err
+}
+
 // Insert 插入一个或多个数据。
 func (tx *Tx) Insert(v interface{}) (sql.Result, error) {
-	return insert(tx, v)
+	return tx.InsertContext(context.Background(), v)
+}
+
+// InsertContext 插入一个或多个数据。
+func (tx *Tx) InsertContext(ctx context.Context, v interface{}) (sql.Result, error) {
+	return insert(ctx, tx, v)
+}
+
+// Upsert 插入数据，若与已有数据冲突，则更新已有数据。
+//
+// 具体说明可参考 DB.Upsert()。
+func (tx *Tx) Upsert(v interface{}, cols ...string) (sql.Result, error) {
+	return tx.UpsertContext(context.Background(), v, cols...)
+}
+
+// UpsertContext 插入数据，若与已有数据冲突，则更新已有数据。
+func (tx *Tx) UpsertContext(ctx context.Context, v interface{}, cols ...string) (sql.Result, error) {
+	return upsert(ctx, tx, v, cols...)
 }
 
 // Select 读数据
 func (tx *Tx) Select(v interface{}) error {
-	return find(tx, v)
+	return tx.SelectContext(context.Background(), v)
+}
+
+// SelectContext 读数据
+func (tx *Tx) SelectContext(ctx context.Context, v interface{}) error {
+	return find(ctx, tx, v)
+}
+
+// Find 查询所有符合 example 条件的数据，并写入到 objs 中。
+//
+// 具体说明可参考 DB.Find()。
+func (tx *Tx) Find(example, objs interface{}, opts *FindOptions) (int64, error) {
+	return tx.FindContext(context.Background(), example, objs, opts)
+}
+
+// FindContext 查询所有符合 example 条件的数据，并写入到 objs 中。
+func (tx *Tx) FindContext(ctx context.Context, example, objs interface{}, opts *FindOptions) (int64, error) {
+	return findMany(ctx, tx, example, objs, opts)
+}
+
+// Preload 为 objs 加载 paths 指定的关联数据。
+//
+// 具体说明可参考 DB.Preload()。
+func (tx *Tx) Preload(objs interface{}, paths ...string) error {
+	return tx.PreloadContext(context.Background(), objs, paths...)
+}
+
+// PreloadContext 为 objs 加载 paths 指定的关联数据。
+func (tx *Tx) PreloadContext(ctx context.Context, objs interface{}, paths ...string) error {
+	return preload(ctx, tx, objs, paths)
 }
 
 // ForUpdate 读数据并锁定
 func (tx *Tx) ForUpdate(v interface{}) error {
-	return forUpdate(tx, v)
+	return tx.ForUpdateContext(context.Background(), v)
+}
+
+// ForUpdateContext 读数据并锁定
+func (tx *Tx) ForUpdateContext(ctx context.Context, v interface{}) error {
+	return forUpdate(ctx, tx, v)
 }
 
 // InsertMany 插入多条相同的数据。若需要向某张表中插入多条记录，
@@ -151,7 +307,16 @@ func (tx *Tx) ForUpdate(v interface{}) error {
 //  us := []*users{&user{}, &user{}}
 //  db.InsertMany(us)
 //  db.Insert(us...) // 这样也行，但是性能会差好多
+//
+// 当数据量较大时，会根据 Dialect.MaxPlaceholders() 拆分成多条语句执行。
+// v 为空数组时，不执行任何操作。
+// 自增列为零值的元素，插入之后会被写入数据库生成的值。
 func (tx *Tx) InsertMany(v interface{}) error {
+	return tx.InsertManyContext(context.Background(), v)
+}
+
+// InsertManyContext 插入多条相同的数据。
+func (tx *Tx) InsertManyContext(ctx context.Context, v interface{}) error {
 	rval := reflect.ValueOf(v)
 	for rval.Kind() == reflect.Ptr {
 		rval = rval.Elem()
@@ -159,51 +324,106 @@ func (tx *Tx) InsertMany(v interface{}) error {
 
 	switch rval.Kind() {
 	case reflect.Struct: // 单个元素
-		_, err := tx.Insert(v)
+		_, err := tx.InsertContext(ctx, v)
 		return err
 	case reflect.Array, reflect.Slice: // 支持多个插入，则由此处跳出 switch
-		sql, err := buildInsertManySQL(tx, rval)
+		if rval.Len() == 0 { // 没有需要插入的数据
+			return nil
+		}
+
+		stmts, err := buildInsertManySQL(tx, rval)
 		if err != nil {
 			return err
 		}
 
-		_, err = sql.Exec()
-		return err
+		if err = execInsertMany(ctx, tx, stmts, rval); err != nil {
+			return err
+		}
+
+		for i := 0; i < rval.Len(); i++ {
+			if h, ok := elem(rval, i).Interface().(AfterInserter); ok {
+				if err = h.AfterInsert(tx); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	default:
 		return fetch.ErrInvalidKind
 	}
 }
 
 // Update 更新一条类型。
+//
+// 若 v 包含乐观锁列，在数据已经被其它操作修改时，返回 ErrOCCConflict。
 func (tx *Tx) Update(v interface{}, cols ...string) (sql.Result, error) {
-	return update(tx, v, cols...)
+	return tx.UpdateContext(context.Background(), v, cols...)
+}
+
+// UpdateContext 更新一条类型。
+func (tx *Tx) UpdateContext(ctx context.Context, v interface{}, cols ...string) (sql.Result, error) {
+	return update(ctx, tx, v, cols...)
 }
 
 // Delete 删除一条数据。
 func (tx *Tx) Delete(v interface{}) (sql.Result, error) {
-	return del(tx, v)
+	return tx.DeleteContext(context.Background(), v)
+}
+
+// DeleteContext 删除一条数据。
+func (tx *Tx) DeleteContext(ctx context.Context, v interface{}) (sql.Result, error) {
+	return del(ctx, tx, v)
+}
+
+// HardDelete 删除一条数据，即使 v 包含软删除列，也会直接从数据库中删除。
+func (tx *Tx) HardDelete(v interface{}) (sql.Result, error) {
+	return tx.HardDeleteContext(context.Background(), v)
+}
+
+// HardDeleteContext 删除一条数据，即使 v 包含软删除列，也会直接从数据库中删除。
+func (tx *Tx) HardDeleteContext(ctx context.Context, v interface{}) (sql.Result, error) {
+	return del(Unscoped(ctx), tx, v)
 }
 
 // Count 查询符合 v 条件的记录数量。
 // v 中的所有非零字段都将参与查询。
 func (tx *Tx) Count(v interface{}) (int64, error) {
-	return count(tx, v)
+	return tx.CountContext(context.Background(), v)
+}
+
+// CountContext 查询符合 v 条件的记录数量。
+func (tx *Tx) CountContext(ctx context.Context, v interface{}) (int64, error) {
+	return count(ctx, tx, v)
 }
 
 // Create 创建数据表。
 func (tx *Tx) Create(v interface{}) error {
-	return create(tx, v)
+	return tx.CreateContext(context.Background(), v)
+}
 
+// CreateContext 创建数据表。
+func (tx *Tx) CreateContext(ctx context.Context, v interface{}) error {
+	return create(ctx, tx, v)
 }
 
 // Drop 删除表结构及数据。
 func (tx *Tx) Drop(v interface{}) error {
-	return drop(tx, v)
+	return tx.DropContext(context.Background(), v)
+}
+
+// DropContext 删除表结构及数据。
+func (tx *Tx) DropContext(ctx context.Context, v interface{}) error {
+	return drop(ctx, tx, v)
 }
 
 // Truncate 清除表内容，重置 ai，但保留表结构。
 func (tx *Tx) Truncate(v interface{}) error {
-	return truncate(tx, v)
+	return tx.TruncateContext(context.Background(), v)
+}
+
+// TruncateContext 清除表内容，重置 ai，但保留表结构。
+func (tx *Tx) TruncateContext(ctx context.Context, v interface{}) error {
+	return truncate(ctx, tx, v)
 }
 
 // SQL 返回 SQL 实例
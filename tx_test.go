@@ -5,6 +5,9 @@
 package orm_test
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/issue9/assert"
@@ -41,6 +44,16 @@ func TestTx_InsertMany(t *testing.T) {
 			FirstName: "f3",
 			LastName:  "l3",
 		}}))
+
+	// 各元素需要插入的列不同：sex 为零值时会使用默认值
+	a.NotError(tx.InsertMany([]*modeltest.UserInfo{
+		&modeltest.UserInfo{UID: 4, FirstName: "f4", LastName: "l4"},
+		&modeltest.UserInfo{UID: 5, FirstName: "f5", LastName: "l5", Sex: "female"},
+		&modeltest.UserInfo{UID: 6, FirstName: "f6", LastName: "l6"},
+	}))
+
+	// 空数组
+	a.NotError(tx.InsertMany([]*modeltest.UserInfo{}))
 	a.NotError(tx.Commit())
 
 	u1 := &modeltest.UserInfo{UID: 1}
@@ -54,6 +67,14 @@ func TestTx_InsertMany(t *testing.T) {
 	u3 := &modeltest.UserInfo{UID: 3}
 	a.NotError(db.Select(u3))
 	a.Equal(u3, &modeltest.UserInfo{UID: 3, FirstName: "f3", LastName: "l3", Sex: "male"})
+
+	u5 := &modeltest.UserInfo{UID: 5}
+	a.NotError(db.Select(u5))
+	a.Equal(u5, &modeltest.UserInfo{UID: 5, FirstName: "f5", LastName: "l5", Sex: "female"})
+
+	u6 := &modeltest.UserInfo{UID: 6}
+	a.NotError(db.Select(u6))
+	a.Equal(u6, &modeltest.UserInfo{UID: 6, FirstName: "f6", LastName: "l6", Sex: "male"})
 }
 
 func TestTx_Insert(t *testing.T) {
@@ -251,3 +272,136 @@ func TestTX(t *testing.T) {
 	a.NotError(tx.Commit())
 	hasCount(db, a, "users", 3)
 }
+
+func TestDB_DoTransaction(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer clearData(db, a)
+
+	a.NotError(db.Create(&modeltest.User{}))
+	ctx := context.Background()
+
+	// 正常提交
+	a.NotError(db.DoTransaction(ctx, nil, func(tx *orm.Tx) error {
+		_, err := tx.Insert(&modeltest.User{Username: "u1"})
+		return err
+	}))
+	hasCount(db, a, "users", 1)
+
+	// 返回错误，回滚事务
+	err := errors.New("rollback")
+	a.Equal(err, db.DoTransaction(ctx, nil, func(tx *orm.Tx) error {
+		if _, err := tx.Insert(&modeltest.User{Username: "u2"}); err != nil {
+			return err
+		}
+		return err
+	}))
+	hasCount(db, a, "users", 1)
+
+	// 发生 panic，回滚事务
+	a.Panic(func() {
+		db.DoTransaction(ctx, nil, func(tx *orm.Tx) error {
+			if _, err := tx.Insert(&modeltest.User{Username: "u3"}); err != nil {
+				return err
+			}
+			panic("panic")
+		})
+	})
+	hasCount(db, a, "users", 1)
+
+	// 指定 TxOptions
+	a.NotError(db.DoTransaction(ctx, &sql.TxOptions{ReadOnly: true}, func(tx *orm.Tx) error {
+		hasCount(tx, a, "users", 1)
+		return nil
+	}))
+}
+
+func TestTx_Savepoint(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer clearData(db, a)
+
+	a.NotError(db.Create(&modeltest.User{}))
+
+	tx, err := db.Begin()
+	a.NotError(err).NotNil(tx)
+	a.NotError(tx.Insert(&modeltest.User{Username: "u1"}))
+
+	a.NotError(tx.Savepoint("sp1"))
+	a.NotError(tx.Insert(&modeltest.User{Username: "u2"}))
+	a.NotError(tx.RollbackTo("sp1"))
+	a.NotError(tx.Release("sp1"))
+	hasCount(tx, a, "users", 1)
+
+	// 嵌套事务回滚
+	nested, err := tx.Begin()
+	a.NotError(err).NotNil(nested)
+	a.NotError(nested.Insert(&modeltest.User{Username: "u3"}))
+	hasCount(nested, a, "users", 2)
+	a.NotError(nested.Rollback())
+	hasCount(tx, a, "users", 1)
+
+	// 嵌套事务提交
+	nested, err = tx.Begin()
+	a.NotError(err).NotNil(nested)
+	a.NotError(nested.Insert(&modeltest.User{Username: "u4"}))
+
+	// 多层嵌套
+	nested2, err := nested.Begin()
+	a.NotError(err).NotNil(nested2)
+	a.NotError(nested2.Insert(&modeltest.User{Username: "u5"}))
+	a.NotError(nested2.Rollback())
+	a.NotError(nested.Commit())
+	hasCount(tx, a, "users", 2)
+
+	a.NotError(tx.Commit())
+	hasCount(db, a, "users", 2)
+}
+
+func TestTx_InsertMany_hooks(t *testing.T) {
+	a := assert.New(t)
+
+	db := newDB(a)
+	defer func() {
+		a.NotError(db.Drop(&hookUser{}))
+		clearData(db, a)
+	}()
+	a.NotError(db.Create(&hookUser{}))
+
+	tx, err := db.Begin()
+	a.NotError(err)
+	us := []*hookUser{&hookUser{Name: "u1"}, &hookUser{Name: "u2"}}
+	a.NotError(tx.InsertMany(us))
+	a.NotError(tx.Commit())
+
+	for _, u := range us {
+		a.Equal(u.events, []string{"BeforeInsert", "AfterInsert"})
+	}
+	hasCount(db, a, "hook_users", 2)
+
+	// 值类型的数组，同样会调用指针接收者的钩子方法
+	tx, err = db.Begin()
+	a.NotError(err)
+	vs := []hookUser{{Name: "v1"}, {Name: "v2"}}
+	a.NotError(tx.InsertMany(vs))
+	a.NotError(tx.Commit())
+	for _, u := range vs {
+		a.Equal(u.events, []string{"BeforeInsert", "AfterInsert"})
+	}
+	hasCount(db, a, "hook_users", 4)
+
+	// 任意一个元素的 BeforeInsert 返回错误，都将中止操作
+	tx, err = db.Begin()
+	a.NotError(err)
+	a.Error(tx.InsertMany([]*hookUser{&hookUser{Name: "u3"}, &hookUser{}}))
+	a.NotError(tx.Rollback())
+	hasCount(db, a, "hook_users", 4)
+
+	tx, err = db.Begin()
+	a.NotError(err)
+	a.Error(tx.InsertMany([]hookUser{{Name: "v3"}, {}}))
+	a.NotError(tx.Rollback())
+	hasCount(db, a, "hook_users", 4)
+}
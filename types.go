@@ -5,13 +5,25 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/issue9/orm/model"
 	"github.com/issue9/orm/sqlbuilder"
 )
 
+// ErrOCCConflict 更新带乐观锁的数据时，若数据已经被其它操作修改，
+// 或是数据不存在，则返回此错误。
+var ErrOCCConflict = errors.New("乐观锁冲突")
+
+// ErrNoChanges 更新实现了 Tracker 接口的对象时，
+// 若所有列的值都与快照相同，则返回此错误。
+var ErrNoChanges = errors.New("没有需要更新的内容")
+
 // Engine 是 DB 与 Tx 的共有接口。
+//
+// 此接口可能会随着功能的增加而添加新的方法，不建议在包外实现该接口。
 type Engine interface {
 	sqlbuilder.Engine
 
@@ -20,23 +32,99 @@ type Engine interface {
 
 	Insert(v interface{}) (sql.Result, error)
 
+	InsertContext(ctx context.Context, v interface{}) (sql.Result, error)
+
+	Upsert(v interface{}, cols ...string) (sql.Result, error)
+
+	UpsertContext(ctx context.Context, v interface{}, cols ...string) (sql.Result, error)
+
 	Delete(v interface{}) (sql.Result, error)
 
+	DeleteContext(ctx context.Context, v interface{}) (sql.Result, error)
+
+	HardDelete(v interface{}) (sql.Result, error)
+
+	HardDeleteContext(ctx context.Context, v interface{}) (sql.Result, error)
+
 	Update(v interface{}, cols ...string) (sql.Result, error)
 
+	UpdateContext(ctx context.Context, v interface{}, cols ...string) (sql.Result, error)
+
 	Select(v interface{}) error
 
+	SelectContext(ctx context.Context, v interface{}) error
+
+	Find(example, objs interface{}, opts *FindOptions) (int64, error)
+
+	FindContext(ctx context.Context, example, objs interface{}, opts *FindOptions) (int64, error)
+
+	Preload(objs interface{}, paths ...string) error
+
+	PreloadContext(ctx context.Context, objs interface{}, paths ...string) error
+
 	Count(v interface{}) (int64, error)
 
+	CountContext(ctx context.Context, v interface{}) (int64, error)
+
 	Create(v interface{}) error
 
+	CreateContext(ctx context.Context, v interface{}) error
+
 	Drop(v interface{}) error
 
+	DropContext(ctx context.Context, v interface{}) error
+
 	Truncate(v interface{}) error
 
+	TruncateContext(ctx context.Context, v interface{}) error
+
 	SQL() *SQL
 }
 
+// BeforeInserter 在插入数据之前调用的接口。
+//
+// 返回的 error 不为 nil 时，将中止插入操作，并返回该错误。
+// e 为当前操作所使用的 DB 或是 Tx，可用于在同一事务中执行其它语句。
+type BeforeInserter interface {
+	BeforeInsert(e Engine) error
+}
+
+// AfterInserter 在插入数据之后调用的接口。
+type AfterInserter interface {
+	AfterInsert(e Engine) error
+}
+
+// BeforeUpdater 在更新数据之前调用的接口。
+//
+// 返回的 error 不为 nil 时，将中止更新操作，并返回该错误。
+type BeforeUpdater interface {
+	BeforeUpdate(e Engine) error
+}
+
+// AfterUpdater 在更新数据之后调用的接口。
+type AfterUpdater interface {
+	AfterUpdate(e Engine) error
+}
+
+// BeforeDeleter 在删除数据之前调用的接口。
+//
+// 返回的 error 不为 nil 时，将中止删除操作，并返回该错误。
+type BeforeDeleter interface {
+	BeforeDelete(e Engine) error
+}
+
+// AfterDeleter 在删除数据之后调用的接口。
+type AfterDeleter interface {
+	AfterDelete(e Engine) error
+}
+
+// AfterFinder 在从数据库中读取数据并填充到对象之后调用的接口。
+//
+// 仅在找到对应的数据时才会被调用。
+type AfterFinder interface {
+	AfterFind(e Engine) error
+}
+
 // Dialect 数据库驱动特有的语言特性实现
 type Dialect interface {
 	sqlbuilder.Dialect
@@ -45,6 +133,42 @@ type Dialect interface {
 	//
 	// 创建表可能生成多条语句，比如创建表，以及相关的创建索引语句。
 	CreateTableSQL(m *model.Model) ([]string, error)
+
+	// 单条语句中允许的最大占位符数量。
+	//
+	// InsertMany 等操作会根据此值将数据拆分成多条语句执行。
+	MaxPlaceholders() int
+
+	// 字符串中的反斜杠是否作为转义字符，比如 mysql 中的 'it\'s'。
+	//
+	// 替换语句中的表名前缀等内容时，需要据此识别字符串的结束位置。
+	BackslashEscapes() bool
+
+	// 一条语句插入多行数据时，sql.Result.LastInsertId() 返回的是否为第一行数据的自增列值，
+	// 否则表示返回的是最后一行数据的自增列值。
+	//
+	// 仅在 LastInsertIDSQL() 返回空值时有效，InsertMany 据此推算各行数据的自增列值。
+	FirstInsertID() bool
+}
+
+// FindOptions 为 Engine.Find() 指定的查询选项
+type FindOptions struct {
+	// 排序的列名，以减号开头表示倒序，比如：
+	//  []string{"-created", "id"}
+	Order []string
+
+	// 最多返回的记录数量，小于等于 0 表示不限制。
+	Limit int
+
+	// 跳过的记录数量，仅在 Limit 大于 0 时有效。
+	Offset int
+
+	// 是否同时查询符合条件的记录总数，
+	// 若为 false，Find() 返回的是实际读取的记录数量。
+	Count bool
+
+	// 需要同时加载的关联数据，具体可参考 DB.Preload()。
+	Preload []string
 }
 
 // SQL 用于生成 SQL 语句
@@ -72,6 +196,21 @@ func (sql *SQL) Select() *sqlbuilder.SelectStmt {
 	return sqlbuilder.Select(sql.engine, sql.engine.Dialect())
 }
 
+// SelectFrom 生成从 v 对应的表中查询数据的语句。
+//
+// 若 v 包含软删除列，会自动过滤掉已经被软删除的数据，
+// 需要包含这些数据时，可以使用 Select().From() 代替。
+func (sql *SQL) SelectFrom(v interface{}) (*sqlbuilder.SelectStmt, error) {
+	m, _, err := getModel(v)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := sql.Select().From("{#" + m.Name + "}")
+	whereNotDeleted(context.Background(), stmt, m)
+	return stmt, nil
+}
+
 // CreateIndex 生成创建索引的语句
 func (sql *SQL) CreateIndex() *sqlbuilder.CreateIndexStmt {
 	return sqlbuilder.CreateIndex(sql.engine)